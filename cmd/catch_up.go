@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/sirupsen/logrus"
+)
+
+// catchUpPolicy decides which height to compare next when lagging behind the head.
+type catchUpPolicy string
+
+const (
+	// compare every height
+	policySequential catchUpPolicy = "sequential"
+	// compare part of the heights, so that the lag can be covered in about maxLag rounds
+	policySample catchUpPolicy = "sample"
+	// jump to the latest height directly
+	policySkip catchUpPolicy = "skip"
+)
+
+func parseCatchUpPolicy(s string) (catchUpPolicy, error) {
+	switch p := catchUpPolicy(s); p {
+	case policySequential, policySample, policySkip:
+		return p, nil
+	case "":
+		return policySequential, nil
+	default:
+		return "", fmt.Errorf("unknown catch up policy %s, expect one of %s, %s, %s",
+			s, policySequential, policySample, policySkip)
+	}
+}
+
+// nextHeight returns the height to compare after current, target is the highest height can be compared.
+func nextHeight(policy catchUpPolicy, current, target abi.ChainEpoch, maxLag int) abi.ChainEpoch {
+	lag := target - current
+	if lag <= 1 || maxLag <= 0 || lag <= abi.ChainEpoch(maxLag) {
+		return current + 1
+	}
+
+	switch policy {
+	case policySample:
+		step := (lag + abi.ChainEpoch(maxLag) - 1) / abi.ChainEpoch(maxLag)
+		return current + step
+	case policySkip:
+		return target
+	default:
+		return current + 1
+	}
+}
+
+func (mgr *compareMgr) targetHeight() abi.ChainEpoch {
+	return abi.ChainEpoch(atomic.LoadInt64(&mgr.head)) - defaultConfidence
+}
+
+// lag returns how many heights h is behind the head, not including confidence.
+func (mgr *compareMgr) lag(h abi.ChainEpoch) abi.ChainEpoch {
+	lag := mgr.targetHeight() - h
+	if lag < 0 {
+		return 0
+	}
+	return lag
+}
+
+// followHead compares the next height chosen by policy, and keeps going until catch up with the head.
+func (mgr *compareMgr) followHead() {
+	cur := mgr.currentTS.Height()
+	target := mgr.targetHeight()
+	if cur >= target {
+		return
+	}
+
+	h := nextHeight(mgr.cfg.policy, cur, target, mgr.cfg.maxLag)
+	logrus.Infof("lag %d epochs, policy %s, compare height %d", target-cur, mgr.cfg.policy, h)
+	if !mgr.compare(h) {
+		return
+	}
+
+	if mgr.lag(mgr.currentTS.Height()) > 0 {
+		mgr.notifyNext()
+	}
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextHeight(t *testing.T) {
+	cases := []struct {
+		policy  catchUpPolicy
+		current abi.ChainEpoch
+		target  abi.ChainEpoch
+		expect  abi.ChainEpoch
+	}{
+		{policySequential, 100, 200, 101},
+		{policySample, 100, 200, 105},
+		{policySample, 100, 110, 101},
+		{policySkip, 100, 200, 200},
+		{policySkip, 100, 110, 101},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expect, nextHeight(c.policy, c.current, c.target, 20), c.policy)
+	}
+
+	p, err := parseCatchUpPolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, policySequential, p)
+	_, err = parseCatchUpPolicy("unknown")
+	assert.Error(t, err)
+}
@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/builtin"
+	lapi "github.com/filecoin-project/lotus/api"
+	v1 "github.com/filecoin-project/venus/venus-shared/api/chain/v1"
+	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/sirupsen/logrus"
+)
+
+func newChainNotifyCompare(ctx context.Context, vAPI v1.FullNode, lAPI lapi.FullNode, window int) *chainNotifyCompare {
+	return &chainNotifyCompare{
+		ctx:    ctx,
+		vAPI:   vAPI,
+		lAPI:   lAPI,
+		window: window,
+	}
+}
+
+// chainNotifyCompare subscribes ChainNotify on both nodes, and compares the head changes received in every
+// window of epochs.
+type chainNotifyCompare struct {
+	ctx context.Context
+
+	vAPI v1.FullNode
+	lAPI lapi.FullNode
+
+	window int
+}
+
+// headEvent is a flattened head change.
+type headEvent struct {
+	typ    types.HeadChangeType
+	height abi.ChainEpoch
+	key    types.TipSetKey
+}
+
+func (e headEvent) String() string {
+	return fmt.Sprintf("%s %d %v", e.typ, e.height, e.key)
+}
+
+func (c *chainNotifyCompare) start() {
+	for {
+		if err := c.compareWindow(); err != nil {
+			logrus.Errorf("compare chain notify failed: %v", err)
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(reconnectMinDelay):
+		}
+	}
+}
+
+func (c *chainNotifyCompare) compareWindow() error {
+	ctx, cancel := context.WithTimeout(c.ctx, time.Duration(c.window)*time.Duration(builtin.EpochDurationSeconds)*time.Second)
+	defer cancel()
+
+	vNotifies, err := c.vAPI.ChainNotify(ctx)
+	if err != nil {
+		return fmt.Errorf("venus chain notify: %v", err)
+	}
+	lNotifies, err := c.lAPI.ChainNotify(ctx)
+	if err != nil {
+		return fmt.Errorf("lotus chain notify: %v", err)
+	}
+	logrus.Infof("subscribed chain notify, compare after %d epochs", c.window)
+
+	type result struct {
+		events []headEvent
+		err    error
+	}
+	vCh := make(chan result, 1)
+	go func() {
+		var events []headEvent
+		err := collectHeadChanges(ctx, vNotifies, func(changes []*types.HeadChange) {
+			for _, change := range changes {
+				events = append(events, headEvent{typ: change.Type, height: change.Val.Height(), key: change.Val.Key()})
+			}
+		})
+		vCh <- result{events: events, err: err}
+	}()
+	var lEvents []headEvent
+	lErr := collectHeadChanges(ctx, lNotifies, func(changes []*lapi.HeadChange) {
+		for _, change := range changes {
+			key := types.NewTipSetKey(change.Val.Cids()...)
+			lEvents = append(lEvents, headEvent{typ: types.HeadChangeType(change.Type), height: change.Val.Height(), key: key})
+		}
+	})
+	vRes := <-vCh
+	if vRes.err != nil || lErr != nil {
+		return fmt.Errorf("venus error: %v, lotus error: %v", vRes.err, lErr)
+	}
+
+	diffs := diffHeadEvents(vRes.events, lEvents)
+	for _, diff := range diffs {
+		logrus.Errorf("compare chain notify failed: %s", diff)
+	}
+	if len(diffs) == 0 {
+		logrus.Infof("compare chain notify success, venus %d, lotus %d", len(vRes.events), len(lEvents))
+	}
+
+	return nil
+}
+
+// collectHeadChanges passes notifications to f until ctx done, the channel closed before ctx done is an error.
+func collectHeadChanges[T any](ctx context.Context, notifies <-chan []T, f func([]T)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notify, ok := <-notifies:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("chain notify channel closed")
+			}
+			f(notify)
+		}
+	}
+}
+
+// diffHeadEvents compares the head changes of lotus to venus. Both must start with one current event, then
+// the applied chain, its order and the reverted tipsets are compared in the heights notified by both nodes,
+// because the heads at the edges of the window may only be notified by one node.
+func diffHeadEvents(vEvents, lEvents []headEvent) []string {
+	var diffs []string
+	names := []string{"venus", "lotus"}
+	for i, events := range [][]headEvent{vEvents, lEvents} {
+		name := names[i]
+		if len(events) == 0 || events[0].typ != types.HCCurrent {
+			diffs = append(diffs, fmt.Sprintf("%s not start with %s event", name, types.HCCurrent))
+		}
+		for j := 1; j < len(events); j++ {
+			if events[j].typ == types.HCCurrent {
+				diffs = append(diffs, fmt.Sprintf("%s got unexpected %s event at %d", name, types.HCCurrent, j))
+			}
+		}
+	}
+	if len(diffs) > 0 {
+		return diffs
+	}
+
+	vApply, vRevert := splitHeadEvents(vEvents[1:])
+	lApply, lRevert := splitHeadEvents(lEvents[1:])
+	if len(vApply) == 0 || len(lApply) == 0 {
+		if len(vApply) != len(lApply) {
+			diffs = append(diffs, fmt.Sprintf("apply events length %d != %d", len(vApply), len(lApply)))
+		}
+		return diffs
+	}
+
+	low, high := vApply[0].height, vApply[len(vApply)-1].height
+	if lApply[0].height > low {
+		low = lApply[0].height
+	}
+	if lApply[len(lApply)-1].height < high {
+		high = lApply[len(lApply)-1].height
+	}
+	inRange := func(events []headEvent) []headEvent {
+		var out []headEvent
+		for _, e := range events {
+			if e.height >= low && e.height <= high {
+				out = append(out, e)
+			}
+		}
+		return out
+	}
+	vApply, lApply = inRange(vApply), inRange(lApply)
+	vRevert, lRevert = inRange(vRevert), inRange(lRevert)
+
+	contain := func(events []headEvent, e headEvent) bool {
+		for _, o := range events {
+			if o.key.Equals(e.key) {
+				return true
+			}
+		}
+		return false
+	}
+	var vCommon, lCommon []headEvent
+	for _, e := range vApply {
+		if !contain(lApply, e) {
+			diffs = append(diffs, fmt.Sprintf("missing %v in lotus", e))
+			continue
+		}
+		vCommon = append(vCommon, e)
+	}
+	for _, e := range lApply {
+		if !contain(vApply, e) {
+			diffs = append(diffs, fmt.Sprintf("extra %v in lotus", e))
+			continue
+		}
+		lCommon = append(lCommon, e)
+	}
+	// duplicate apply events make the lengths differ
+	if len(vCommon) != len(lCommon) {
+		diffs = append(diffs, fmt.Sprintf("applied tipsets length %d != %d", len(vCommon), len(lCommon)))
+	}
+	n := len(vCommon)
+	if len(lCommon) < n {
+		n = len(lCommon)
+	}
+	for i := 0; i < n; i++ {
+		if !vCommon[i].key.Equals(lCommon[i].key) {
+			diffs = append(diffs, fmt.Sprintf("apply out of order at %d, venus %v, lotus %v", i, vCommon[i], lCommon[i]))
+			break
+		}
+	}
+
+	for _, e := range vRevert {
+		if !contain(lRevert, e) {
+			diffs = append(diffs, fmt.Sprintf("venus reverted %v, but lotus not", e))
+		}
+	}
+	for _, e := range lRevert {
+		if !contain(vRevert, e) {
+			diffs = append(diffs, fmt.Sprintf("lotus reverted %v, but venus not", e))
+		}
+	}
+
+	return diffs
+}
+
+// splitHeadEvents returns the applied tipsets which are not reverted later in order, and the reverted tipsets.
+func splitHeadEvents(events []headEvent) ([]headEvent, []headEvent) {
+	var apply, revert []headEvent
+	for _, e := range events {
+		switch e.typ {
+		case types.HCApply:
+			apply = append(apply, e)
+		case types.HCRevert:
+			revert = append(revert, e)
+			for i := len(apply) - 1; i >= 0; i-- {
+				if apply[i].key.Equals(e.key) {
+					apply = append(apply[:i], apply[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+
+	return apply, revert
+}
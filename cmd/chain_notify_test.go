@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/venus/venus-shared/testutil"
+	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffHeadEvents(t *testing.T) {
+	var cids []cid.Cid
+	testutil.Provide(t, &cids, testutil.WithSliceLen(5))
+	ev := func(typ types.HeadChangeType, height int, idx int) headEvent {
+		return headEvent{typ: typ, height: abi.ChainEpoch(height), key: types.NewTipSetKey(cids[idx])}
+	}
+
+	v := []headEvent{ev(types.HCCurrent, 0, 0), ev(types.HCApply, 1, 1), ev(types.HCApply, 2, 2), ev(types.HCApply, 3, 3)}
+	assert.Len(t, diffHeadEvents(v, v), 0)
+
+	// heads at the edges of the window
+	l := []headEvent{ev(types.HCCurrent, 1, 1), ev(types.HCApply, 2, 2), ev(types.HCApply, 3, 3)}
+	assert.Len(t, diffHeadEvents(v, l), 0)
+
+	// not start with current
+	assert.Len(t, diffHeadEvents(v, v[1:]), 1)
+
+	// different tipset at height 2
+	l = []headEvent{ev(types.HCCurrent, 0, 0), ev(types.HCApply, 1, 1), ev(types.HCApply, 2, 4), ev(types.HCApply, 3, 3)}
+	assert.Len(t, diffHeadEvents(v, l), 2)
+
+	// lotus reverted and applied again
+	l = []headEvent{ev(types.HCCurrent, 0, 0), ev(types.HCApply, 1, 1), ev(types.HCApply, 2, 2),
+		ev(types.HCRevert, 2, 2), ev(types.HCApply, 2, 2), ev(types.HCApply, 3, 3)}
+	assert.Len(t, diffHeadEvents(v, l), 1)
+
+	// lotus applied the same tipset twice
+	l = []headEvent{ev(types.HCCurrent, 0, 0), ev(types.HCApply, 1, 1), ev(types.HCApply, 2, 2),
+		ev(types.HCApply, 2, 2), ev(types.HCApply, 3, 3)}
+	assert.NotPanics(t, func() {
+		assert.Len(t, diffHeadEvents(v, l), 2)
+	})
+	assert.NotPanics(t, func() {
+		assert.Len(t, diffHeadEvents(l, v), 2)
+	})
+}
+
+func TestCollectHeadChanges(t *testing.T) {
+	ch := make(chan []int, 2)
+	ch <- []int{1}
+	close(ch)
+
+	var got []int
+	err := collectHeadChanges(context.Background(), ch, func(v []int) {
+		got = append(got, v...)
+	})
+	assert.Error(t, err)
+	assert.Equal(t, []int{1}, got)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NoError(t, collectHeadChanges(ctx, make(chan []int), func([]int) {}))
+}
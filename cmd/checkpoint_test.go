@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/filecoin-project/venus/venus-shared/testutil"
+	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	cp, err := loadCheckpoint(path)
+	require.NoError(t, err)
+	require.Nil(t, cp)
+
+	var ts types.TipSet
+	testutil.Provide(t, &ts)
+	require.NoError(t, saveCheckpoint(path, &checkpoint{Height: ts.Height(), TipSetKey: ts.Key()}))
+
+	cp, err = loadCheckpoint(path)
+	require.NoError(t, err)
+	require.Equal(t, ts.Height(), cp.Height)
+	require.True(t, ts.Key().Equals(cp.TipSetKey))
+}
@@ -21,39 +21,71 @@ import (
 
 const (
 	methodPrefix = "Compare"
+	// methods only run when there are null rounds before current tipset
+	nullRoundMethodPrefix = "NullRound"
 )
 
 func newAPICompare(ctx context.Context,
 	vAPI v1.FullNode,
 	lAPI api.FullNode,
+	vTrace *ethTraceAPI,
+	lTrace *ethTraceAPI,
 	dp *dataProvider,
 	concurrency int,
+	timeouts *callTimeouts,
+	cfg *compareConfig,
 ) *apiCompare {
 	if concurrency <= 0 {
 		concurrency = 5
 	}
+	if cfg == nil {
+		cfg = &compareConfig{}
+	}
+	controlCh := make(chan struct{}, concurrency)
 	return &apiCompare{
 		ctx:     ctx,
 		vAPI:    vAPI,
 		lAPI:    lAPI,
 		dp:      dp,
-		handler: newHandler(ctx, vAPI, lAPI, concurrency),
+		cfg:     cfg,
+		handler: newHandler(ctx, vAPI, lAPI, controlCh, timeouts),
+		// compare the eth trace apis in another handler, as they are not in the full node apis,
+		// the two handlers share the concurrency limit
+		traceHandler: newHandler(ctx, vTrace, lTrace, controlCh, timeouts),
 	}
 }
 
+type compareConfig struct {
+	// compare all deals returned by StateMarketDeals, it is expensive on large networks
+	fullMarketDeals bool
+	// relative tolerance of estimated fee cap and gas premium
+	gasFeeTolerance float64
+	// replay and call every message in current tipset, and compare the state computed by StateCompute
+	exhaustiveReplay bool
+}
+
 type apiCompare struct {
 	ctx context.Context
 
 	vAPI v1.FullNode
 	lAPI api.FullNode
 
-	dp      *dataProvider
-	handler *handler
+	dp           *dataProvider
+	cfg          *compareConfig
+	handler      *handler
+	traceHandler *handler
 }
 
 func (ac *apiCompare) sendAndWait(methodName string, args []interface{}, opts ...reqOpt) error {
-	req := newReq(methodName, args, opts...)
-	ac.handler.send(req)
+	return ac.waitReq(ac.handler, newReq(methodName, args, opts...))
+}
+
+func (ac *apiCompare) sendTraceAndWait(methodName string, args []interface{}, opts ...reqOpt) error {
+	return ac.waitReq(ac.traceHandler, newReq(methodName, args, opts...))
+}
+
+func (ac *apiCompare) waitReq(h *handler, req *req) error {
+	h.send(req)
 
 	select {
 	case <-ac.ctx.Done():
@@ -64,15 +96,8 @@ func (ac *apiCompare) sendAndWait(methodName string, args []interface{}, opts ..
 }
 
 func (ac *apiCompare) CompareStateAccountKey() error {
-	addr := ac.dp.getSender()
-	if addr.Empty() {
-		return nil
-	}
-
-	req := newReq(stateAccountKey, toInterface(addr, ac.dp.currentTS.Key()))
-	ac.handler.send(req)
-
-	return <-req.err
+	// not all addresses are account actors, so both nodes return error is expected
+	return ac.forEachAddress(stateAccountKey, withExpectCallAPIError())
 }
 
 func (ac *apiCompare) CompareChainGetTipSet() error {
@@ -133,7 +158,7 @@ func (ac *apiCompare) CompareChainGetBlock() error {
 		req := newReq(chainGetBlock, []interface{}{ac.ctx, blk.Cid()})
 		ac.handler.send(req)
 		if err := <-req.err; err != nil {
-			return fmt.Errorf("block: %s, error: %v", blk.Cid(), err)
+			return fmt.Errorf("block: %s, error: %w", blk.Cid(), err)
 		}
 	}
 
@@ -143,25 +168,13 @@ func (ac *apiCompare) CompareChainGetBlock() error {
 func (ac *apiCompare) CompareChainGetBlockMessages() error {
 	for _, blk := range ac.dp.currentTS.Blocks() {
 		req := newReq(chainGetBlockMessages, []interface{}{ac.ctx, blk.Cid()}, withResultCheck(func(r1, r2 interface{}) error {
-			// todo: compare all
 			msgs, _ := r1.(*types.BlockMessages)
 			msgs2, _ := r2.(*api.BlockMessages)
-			cidMap := make(map[cid.Cid]struct{}, len(msgs.Cids))
-			for _, c := range msgs.Cids {
-				cidMap[c] = struct{}{}
-			}
-			for _, c := range msgs2.Cids {
-				if _, ok := cidMap[c]; !ok {
-					d, d2, _ := toJSON(r1, r2)
-					return fmt.Errorf("not match %v != %v", d, d2)
-				}
-			}
-
-			return nil
+			return checkBlockMessages(msgs, msgs2)
 		}))
 		ac.handler.send(req)
 		if err := <-req.err; err != nil {
-			return fmt.Errorf("block: %v, error %v", blk.Cid(), err)
+			return fmt.Errorf("block: %v, error %w", blk.Cid(), err)
 		}
 	}
 
@@ -170,7 +183,9 @@ func (ac *apiCompare) CompareChainGetBlockMessages() error {
 
 func (ac *apiCompare) CompareChainGetMessage() error {
 	for _, blk := range ac.dp.currentTS.Blocks() {
-		blkMsgs, err := ac.vAPI.ChainGetBlockMessages(ac.ctx, blk.Cid())
+		ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(chainGetBlockMessages))
+		blkMsgs, err := ac.vAPI.ChainGetBlockMessages(ctx, blk.Cid())
+		cancel()
 		if err != nil {
 			return fmt.Errorf("failed to get block %s messages: %v", blk.Cid(), err)
 		}
@@ -180,7 +195,7 @@ func (ac *apiCompare) CompareChainGetMessage() error {
 			ac.handler.send(req)
 
 			if err := <-req.err; err != nil {
-				return fmt.Errorf("msg: %s, error: %v", msgCID, err)
+				return fmt.Errorf("msg: %s, error: %w", msgCID, err)
 			}
 		}
 	}
@@ -205,7 +220,7 @@ func (ac *apiCompare) CompareChainGetParentMessages() error {
 		req := newReq(chainGetParentMessages, []interface{}{ac.ctx, blkCID}, withResultCheck(resultCheckWithEqual))
 		ac.handler.send(req)
 		if err := <-req.err; err != nil {
-			return fmt.Errorf("block: %s, error: %v", blkCID, err)
+			return fmt.Errorf("block: %s, error: %w", blkCID, err)
 		}
 	}
 
@@ -217,7 +232,7 @@ func (ac *apiCompare) CompareChainGetParentReceipts() error {
 		req := newReq(chainGetParentReceipts, toInterface(ac.ctx, blkCID))
 		ac.handler.send(req)
 		if err := <-req.err; err != nil {
-			return fmt.Errorf("block: %s, error: %v", blkCID, err)
+			return fmt.Errorf("block: %s, error: %w", blkCID, err)
 		}
 	}
 
@@ -292,7 +307,10 @@ func (ac *apiCompare) CompareStateNetworkVersion() error {
 
 func (ac *apiCompare) CompareChainGetPath() error {
 	ts := ac.dp.currentTS
-	from, err := ac.vAPI.ChainGetTipSetAfterHeight(ac.ctx, ts.Height()-5, ts.Key())
+	ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(chainGetTipSetAfterHeight))
+	defer cancel()
+
+	from, err := ac.vAPI.ChainGetTipSetAfterHeight(ctx, ts.Height()-5, ts.Key())
 	if err != nil {
 		return err
 	}
@@ -332,6 +350,9 @@ func (ac *apiCompare) CompareStateActorManifestCID() error {
 }
 
 func (ac *apiCompare) CompareStateCall() error {
+	if ac.cfg.exhaustiveReplay {
+		return ac.callAll()
+	}
 	msg := ac.dp.getMsg()
 	if msg == nil {
 		return nil
@@ -343,6 +364,9 @@ func (ac *apiCompare) CompareStateCall() error {
 }
 
 func (ac *apiCompare) CompareStateReplay() error {
+	if ac.cfg.exhaustiveReplay {
+		return ac.replayAll()
+	}
 	msg := ac.dp.getMsg()
 	if msg == nil {
 		return nil
@@ -480,41 +504,100 @@ func (ac *apiCompare) CompareEthGetBlockByHash() error {
 
 	var fullTxInfo bool
 	if err := ac.sendAndWait(ethGetBlockByHash, toInterface(ac.ctx, blkHash, fullTxInfo)); err != nil {
-		return fmt.Errorf("fullTxInfo: false, block hash %s, error: %v", blkHash.ToCid(), err)
+		return fmt.Errorf("fullTxInfo: false, block hash %s, error: %w", blkHash.ToCid(), err)
 	}
 
 	fullTxInfo = true
 	if err := ac.sendAndWait(ethGetBlockByHash, toInterface(ac.ctx, blkHash, fullTxInfo)); err != nil {
-		return fmt.Errorf("fullTxInfo: true,  block hash %s, error: %v", blkHash.ToCid(), err)
+		return fmt.Errorf("fullTxInfo: true,  block hash %s, error: %w", blkHash.ToCid(), err)
 	}
 
 	return nil
 }
 
+// CompareEthGetBlockByNumber compares the block of the fixed params, such as earliest, heights and null rounds,
+// and the block of the moving params, such as latest, at a head pinned on both nodes.
 func (ac *apiCompare) CompareEthGetBlockByNumber() error {
 	blkOpt, err := ac.dp.getBlkOptByHeight()
 	if err != nil {
 		return err
 	}
-	// blkParams := []string{blkOpt, blkParamsPending, blkParamsLatest}
-	blkParams := []string{blkOpt}
+	fixedParams := []string{blkParamsEarliest, blkOpt}
+	if h := ac.dp.currentTS.Height(); h > 0 {
+		fixedParams = append(fixedParams, toBlkParam(h-1))
+	}
+	// the nodes may not support these params, or fail at null rounds, all other params must be served
+	errorAccepted := map[string]bool{blkParamsPending: true, blkParamsSafe: true, blkParamsFinalized: true}
+	for _, h := range ac.dp.getNullRounds() {
+		fixedParams = append(fixedParams, toBlkParam(h))
+		errorAccepted[toBlkParam(h)] = true
+	}
+
+	compare := func(blkParams []string) error {
+		for _, blkParam := range blkParams {
+			var opts []reqOpt
+			if errorAccepted[blkParam] {
+				opts = append(opts, withErrorCheck(checkErrorPresence))
+			}
+			for _, fullTxInfo := range []bool{false, true} {
+				err := ac.sendAndWait(ethGetBlockByNumber, toInterface(ac.ctx, blkParam, fullTxInfo), opts...)
+				if err != nil {
+					return fmt.Errorf("block param %s, fullTxInfo %v, error: %w", blkParam, fullTxInfo, err)
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := compare(fixedParams); err != nil {
+		return err
+	}
+
+	return ac.withPinnedHead(func() error {
+		return compare([]string{blkParamsLatest, blkParamsPending, blkParamsSafe, blkParamsFinalized})
+	})
+}
+
+// ethTx is a message in current tipset with its transaction hash.
+type ethTx struct {
+	cid  cid.Cid
+	hash types.EthHash
+}
 
-	for _, blkParam := range blkParams {
-		if err := ac.sendAndWait(ethGetBlockByNumber, toInterface(ac.ctx, blkParam, false)); err != nil {
-			return fmt.Errorf("block param %s, error: %v", blkParam, err)
+// ethTxs returns all messages in current tipset with their transaction hash, the hash of message signed by
+// delegated signature is the hash of the eth transaction, not the one converted from cid.
+func (ac *apiCompare) ethTxs() ([]ethTx, error) {
+	msgs := ac.dp.getTipSetMsgs()
+	txs := make([]ethTx, 0, len(msgs))
+	for _, msg := range msgs {
+		ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(ethGetTransactionHashByCid))
+		hash, err := ac.vAPI.EthGetTransactionHashByCid(ctx, msg.Cid)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transaction hash of %s: %v", msg.Cid, err)
 		}
+		if hash == nil {
+			return nil, fmt.Errorf("not found transaction hash of %s", msg.Cid)
+		}
+		txs = append(txs, ethTx{cid: msg.Cid, hash: *hash})
 	}
 
-	return nil
+	return txs, nil
 }
 
 func (ac *apiCompare) CompareEthGetTransactionByHash() error {
-	msgHash, _, err := ac.dp.getTxHash()
+	txs, err := ac.ethTxs()
 	if err != nil {
 		return err
 	}
 
-	return ac.sendAndWait(ethGetTransactionByHash, toInterface(ac.ctx, &msgHash))
+	for _, tx := range txs {
+		if err := ac.sendAndWait(ethGetTransactionByHash, toInterface(ac.ctx, &tx.hash)); err != nil {
+			return fmt.Errorf("msg %s, tx %s, error: %w", tx.cid, tx.hash, err)
+		}
+	}
+
+	return nil
 }
 
 func (ac *apiCompare) CompareEthGetTransactionCount() error {
@@ -536,21 +619,61 @@ func (ac *apiCompare) CompareEthGetTransactionCount() error {
 }
 
 func (ac *apiCompare) CompareEthGetTransactionReceipt() error {
-	txHash, _, err := ac.dp.getTxHash()
+	txs, err := ac.ethTxs()
 	if err != nil {
 		return err
 	}
 
-	return ac.sendAndWait(ethGetTransactionReceipt, toInterface(ac.ctx, txHash))
+	for _, tx := range txs {
+		if err := ac.sendAndWait(ethGetTransactionReceipt, toInterface(ac.ctx, tx.hash)); err != nil {
+			return fmt.Errorf("msg %s, tx %s, error: %w", tx.cid, tx.hash, err)
+		}
+	}
+
+	return nil
 }
 
+// CompareEthGetTransactionByBlockHashAndIndex compares every index of current tipset, and the index out of range.
 func (ac *apiCompare) CompareEthGetTransactionByBlockHashAndIndex() error {
-	return ac.sendAndWait(ethGetTransactionByBlockHashAndIndex, toInterface(ac.ctx, emptyEthHash, types.EthUint64(0)))
+	blkHash, _, err := ac.dp.getBlockHash()
+	if err != nil {
+		return err
+	}
+
+	msgLen := len(ac.dp.getTipSetMsgs())
+	for i := 0; i <= msgLen; i++ {
+		var opts []reqOpt
+		// both nodes are expected to fail at the index out of range
+		if i == msgLen {
+			opts = append(opts, withErrorCheck(checkErrorPresence))
+		}
+		err := ac.sendAndWait(ethGetTransactionByBlockHashAndIndex, toInterface(ac.ctx, blkHash, types.EthUint64(i)), opts...)
+		if err != nil {
+			return fmt.Errorf("block hash %s, index %d, error: %w", blkHash, i, err)
+		}
+	}
+
+	return nil
 }
 
+// CompareEthGetTransactionByBlockNumberAndIndex compares every index of current tipset, and the index out of range.
 func (ac *apiCompare) CompareEthGetTransactionByBlockNumberAndIndex() error {
-	height := ac.dp.currentTS.Height()
-	return ac.sendAndWait(ethGetTransactionByBlockNumberAndIndex, toInterface(ac.ctx, types.EthUint64(height), types.EthUint64(0)))
+	height := types.EthUint64(ac.dp.currentTS.Height())
+
+	msgLen := len(ac.dp.getTipSetMsgs())
+	for i := 0; i <= msgLen; i++ {
+		var opts []reqOpt
+		// both nodes are expected to fail at the index out of range
+		if i == msgLen {
+			opts = append(opts, withErrorCheck(checkErrorPresence))
+		}
+		err := ac.sendAndWait(ethGetTransactionByBlockNumberAndIndex, toInterface(ac.ctx, height, types.EthUint64(i)), opts...)
+		if err != nil {
+			return fmt.Errorf("height %d, index %d, error: %w", height, i, err)
+		}
+	}
+
+	return nil
 }
 
 func (ac *apiCompare) CompareEthGetCode() error {
@@ -635,46 +758,6 @@ func (ac *apiCompare) CompareEthMaxPriorityFeePerGas() error {
 	}))
 }
 
-// todo: implement
-func (ac *apiCompare) CompareEthEstimateGas() error {
-	// if err := ac.sendAndWait(ethEstimateGas, toInterface(ac.ctx, types.EthCall{})); err != nil {
-	// 	return err
-	// }
-
-	// vfrom, err := types.EthAddressFromFilecoinAddress(ac.dp.defaultMiner())
-	// if err != nil {
-	// 	return err
-	// }
-	// vcall := types.EthCall{
-	// 	From:  &vfrom,
-	// 	To:    &vfrom,
-	// 	Value: types.EthBigInt(big.NewInt(10)),
-	// }
-
-	// return ac.sendAndWait(ethEstimateGas, toInterface(ac.ctx, vcall))
-	return nil
-}
-
-// todo: implement
-func (ac *apiCompare) CompareEthCall() error {
-	// if err := ac.sendAndWait(ethCall, toInterface(ac.ctx, types.EthCall{}, blkParamsLatest)); err != nil {
-	// 	return err
-	// }
-
-	// vfrom, err := types.EthAddressFromFilecoinAddress(ac.dp.defaultMiner())
-	// if err != nil {
-	// 	return err
-	// }
-	// vcall := types.EthCall{
-	// 	From:  &vfrom,
-	// 	To:    &vfrom,
-	// 	Value: types.EthBigInt(big.NewInt(10)),
-	// }
-
-	// return ac.sendAndWait(ethCall, toInterface(ac.ctx, vcall, blkParamsLatest))
-	return nil
-}
-
 func (ac *apiCompare) CompareWeb3ClientVersion() error {
 	return ac.sendAndWait(web3ClientVersion, toInterface(ac.ctx), withResultCheck(func(r1, r2 interface{}) error {
 		fmt.Printf("compare Web3ClientVersion: %v %v\n", r1, r2)
@@ -683,23 +766,26 @@ func (ac *apiCompare) CompareWeb3ClientVersion() error {
 }
 
 func (ac *apiCompare) CompareEthGetTransactionHashByCid() error {
-	msg := ac.dp.getMsg()
-	if msg == nil {
-		return nil
+	for _, msg := range ac.dp.getTipSetMsgs() {
+		if err := ac.sendAndWait(ethGetTransactionHashByCid, toInterface(ac.ctx, msg.Cid)); err != nil {
+			return fmt.Errorf("msg %s, error: %w", msg.Cid, err)
+		}
 	}
 
-	return ac.sendAndWait(ethGetTransactionHashByCid, toInterface(ac.ctx, msg.Cid()))
+	return nil
 }
 
 func (ac *apiCompare) CompareEthGetMessageCidByTransactionHash() error {
-	msg := ac.dp.getMsg()
-	if msg == nil {
-		return nil
-	}
-	h, err := types.EthHashFromCid(msg.Cid())
+	txs, err := ac.ethTxs()
 	if err != nil {
 		return err
 	}
 
-	return ac.sendAndWait(ethGetMessageCidByTransactionHash, toInterface(ac.ctx, &h))
+	for _, tx := range txs {
+		if err := ac.sendAndWait(ethGetMessageCidByTransactionHash, toInterface(ac.ctx, &tx.hash)); err != nil {
+			return fmt.Errorf("msg %s, tx %s, error: %w", tx.cid, tx.hash, err)
+		}
+	}
+
+	return nil
 }
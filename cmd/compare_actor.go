@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/filecoin-project/go-address"
+)
+
+// forEachAddress calls method with each address collected from messages, the addresses cover all protocols.
+func (ac *apiCompare) forEachAddress(method string, opts ...reqOpt) error {
+	key := ac.dp.currentTS.Key()
+	for _, addr := range ac.dp.getAddresses() {
+		if err := ac.sendAndWait(method, toInterface(ac.ctx, addr, key), opts...); err != nil {
+			return fmt.Errorf("address %s, error: %w", addr, err)
+		}
+	}
+
+	return nil
+}
+
+func (ac *apiCompare) CompareStateGetActor() error {
+	// the actor of f4 address may not be created
+	return ac.forEachAddress(stateGetActor, withExpectCallAPIError())
+}
+
+func (ac *apiCompare) CompareStateLookupID() error {
+	return ac.forEachAddress(stateLookupID, withExpectCallAPIError())
+}
+
+func (ac *apiCompare) CompareStateLookupRobustAddress() error {
+	// account actors have no robust address
+	return ac.forEachAddress(stateLookupRobustAddress, withExpectCallAPIError())
+}
+
+func (ac *apiCompare) CompareStateListActors() error {
+	return ac.sendAndWait(stateListActors, toInterface(ac.ctx, ac.dp.currentTS.Key()), withResultCheck(checkAddressList))
+}
+
+func (ac *apiCompare) CompareStateListMiners() error {
+	return ac.sendAndWait(stateListMiners, toInterface(ac.ctx, ac.dp.currentTS.Key()), withResultCheck(checkAddressList))
+}
+
+// checkAddressList reports the first different address instead of the whole list.
+func checkAddressList(r1, r2 interface{}) error {
+	l1, _ := r1.([]address.Address)
+	l2, _ := r2.([]address.Address)
+
+	if len(l1) != len(l2) {
+		return fmt.Errorf("length not match %d != %d", len(l1), len(l2))
+	}
+	for i := range l1 {
+		if l1[i] != l2[i] {
+			return fmt.Errorf("index %d not match %s != %s", i, l1[i], l2[i])
+		}
+	}
+
+	return nil
+}
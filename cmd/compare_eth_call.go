@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/venus/venus-shared/types"
+)
+
+// selector of no contract, calling it is expected to revert
+var unknownSelector = types.EthBytes{0xde, 0xad, 0xbe, 0xef}
+
+var (
+	// eg: message execution failed: exit 33, revert reason: none, vm error: ...
+	ethExitCodeRe     = regexp.MustCompile(`exit ([^,]+),`)
+	ethRevertReasonRe = regexp.MustCompile(`revert reason: (.*), vm error:`)
+)
+
+type ethCallCase struct {
+	name string
+	call types.EthCall
+}
+
+// CompareEthCall compares the return data or revert errors at the compared height.
+func (ac *apiCompare) CompareEthCall() error {
+	blkOpt, err := ac.dp.getBlkOptByHeight()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range ac.ethCallCases() {
+		if err := ac.sendAndWait(ethCall, toInterface(ac.ctx, c.call, blkOpt), withErrorCheck(checkEthRevert)); err != nil {
+			return fmt.Errorf("%s, error: %w", c.name, err)
+		}
+	}
+
+	return nil
+}
+
+// CompareEthEstimateGas compares the gas estimates or the errors, gas is estimated at the latest tipset,
+// so compare at the same head.
+func (ac *apiCompare) CompareEthEstimateGas() error {
+	cases := ac.ethCallCases()
+
+	return ac.withPinnedHead(func() error {
+		for _, c := range cases {
+			if err := ac.sendAndWait(ethEstimateGas, toInterface(ac.ctx, c.call), withErrorCheck(checkEthRevert)); err != nil {
+				return fmt.Errorf("%s, error: %w", c.name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// checkEthRevert compares the exit code and the revert reason decoded from the revert data when both venus
+// and lotus fail to execute the call, the vm errors are not compared as they differ between the implementations.
+// Errors not produced by execution are only logged.
+func checkEthRevert(vErr, lErr error) error {
+	vExit := ethExitCodeRe.FindStringSubmatch(vErr.Error())
+	lExit := ethExitCodeRe.FindStringSubmatch(lErr.Error())
+	if vExit == nil && lExit == nil {
+		return checkErrorPresence(vErr, lErr)
+	}
+	if vExit == nil || lExit == nil || vExit[1] != lExit[1] {
+		return fmt.Errorf("exit code not match, venus error: %v, lotus error: %v", vErr, lErr)
+	}
+
+	vReason := ethRevertReasonRe.FindStringSubmatch(vErr.Error())
+	lReason := ethRevertReasonRe.FindStringSubmatch(lErr.Error())
+	if (vReason == nil) != (lReason == nil) || (vReason != nil && vReason[1] != lReason[1]) {
+		return fmt.Errorf("revert reason not match, venus error: %v, lotus error: %v", vErr, lErr)
+	}
+
+	return nil
+}
+
+// ethCallCases builds calls from the recent contracts and InvokeContract messages, including the contract
+// creations with empty To, value transfers and calls expected to revert.
+func (ac *apiCompare) ethCallCases() []ethCallCase {
+	var cases []ethCallCase
+
+	for _, sample := range ac.dp.getEthCalls() {
+		from, err := ac.toEthAddress(sample.from)
+		if err != nil {
+			continue
+		}
+		to, err := ac.toEthAddress(sample.to)
+		if err != nil {
+			continue
+		}
+		cases = append(cases, ethCallCase{
+			name: fmt.Sprintf("invoke %s from %s", to, from),
+			call: types.EthCall{From: &from, To: &to, Data: sample.data},
+		})
+	}
+
+	var from *types.EthAddress
+	if sender, ok := ac.ethSender(); ok {
+		from = &sender
+	}
+	for _, contract := range ac.dp.getContracts() {
+		to := contract.addr
+		cases = append(cases,
+			ethCallCase{
+				name: fmt.Sprintf("call %s without data", to),
+				call: types.EthCall{From: from, To: &to},
+			},
+			ethCallCase{
+				name: fmt.Sprintf("call %s with unknown selector", to),
+				call: types.EthCall{From: from, To: &to, Data: unknownSelector},
+			},
+			ethCallCase{
+				name: fmt.Sprintf("deploy initcode of %s", to),
+				call: types.EthCall{From: from, Data: contract.initcode},
+			},
+		)
+	}
+
+	if from != nil {
+		cases = append(cases, ethCallCase{
+			name: fmt.Sprintf("transfer value from %s", from),
+			call: types.EthCall{From: from, To: from, Value: types.EthBigInt(big.NewInt(1))},
+		})
+	}
+
+	return cases
+}
+
+// ethSender returns an eth address of the recent senders.
+func (ac *apiCompare) ethSender() (types.EthAddress, bool) {
+	for _, addr := range append(append([]address.Address{}, ac.dp.getAddresses()...), ac.dp.getSenders()...) {
+		ethAddr, err := ac.toEthAddress(addr)
+		if err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(stateGetActor))
+		actor, err := ac.vAPI.StateGetActor(ctx, addr, ac.dp.currentTS.Key())
+		cancel()
+		if err != nil {
+			continue
+		}
+		// the actor has sent messages and can pay for the value transfer
+		if actor.Nonce > 0 && !actor.Balance.IsZero() {
+			return ethAddr, true
+		}
+	}
+
+	return types.EthAddress{}, false
+}
+
+// toEthAddress converts addr to eth address, the address not f4 or ID is converted to ID address first.
+func (ac *apiCompare) toEthAddress(addr address.Address) (types.EthAddress, error) {
+	if addr.Protocol() != address.ID && addr.Protocol() != address.Delegated {
+		ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(stateLookupID))
+		defer cancel()
+
+		idAddr, err := ac.vAPI.StateLookupID(ctx, addr, ac.dp.currentTS.Key())
+		if err != nil {
+			return types.EthAddress{}, err
+		}
+		addr = idAddr
+	}
+
+	return types.EthAddressFromFilecoinAddress(addr)
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckEthRevert(t *testing.T) {
+	revert := func(exit, reason string) error {
+		return errors.New("message execution failed: exit " + exit + ", revert reason: " + reason + ", vm error: " + exit)
+	}
+
+	assert.NoError(t, checkEthRevert(revert("33", "0x01"), revert("33", "0x01")))
+	assert.Error(t, checkEthRevert(revert("33", "0x01"), revert("33", "0x02")))
+	assert.Error(t, checkEthRevert(revert("33", "none"), revert("SysErrOutOfGas(7)", "none")))
+	assert.Error(t, checkEthRevert(revert("33", "none"), errors.New("failed to lookup address")))
+
+	// the vm errors of the implementations are not compared
+	assert.NoError(t, checkEthRevert(
+		errors.New("failed to estimate gas: message execution failed: exit 33, revert reason: none, vm error: venus"),
+		errors.New("failed to estimate gas: message execution failed: exit 33, revert reason: none, vm error: lotus"),
+	))
+	assert.NoError(t, checkEthRevert(
+		errors.New("gas estimation failed: message execution failed: exit 33, reason: venus"),
+		errors.New("gas estimation failed: message execution failed: exit 33, reason: lotus"),
+	))
+	assert.NoError(t, checkEthRevert(errors.New("failed to lookup address"), errors.New("actor not found")))
+}
@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types/ethtypes"
+	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// the number of epochs before current tipset to query logs
+	ethLogsRange = 10
+	// the number of addresses or topics picked from real logs to build filters
+	ethLogsFilterSample = 5
+)
+
+// CompareEthGetLogs compares logs of current tipset, of a block range, and of the address and topic filters
+// derived from the logs of the block range.
+func (ac *apiCompare) CompareEthGetLogs() error {
+	blkHash, _, err := ac.dp.getBlockHash()
+	if err != nil {
+		return err
+	}
+	if err := ac.sendAndWait(ethGetLogs, toInterface(ac.ctx, &types.EthFilterSpec{BlockHash: &blkHash})); err != nil {
+		return fmt.Errorf("block hash %s, error: %w", blkHash, err)
+	}
+
+	rangeSpec, err := ac.logsRangeSpec()
+	if err != nil {
+		return err
+	}
+	if err := ac.sendAndWait(ethGetLogs, toInterface(ac.ctx, rangeSpec)); err != nil {
+		return fmt.Errorf("block range %s-%s, error: %w", *rangeSpec.FromBlock, *rangeSpec.ToBlock, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(ethGetLogs))
+	defer cancel()
+
+	res, err := ac.vAPI.EthGetLogs(ctx, rangeSpec)
+	if err != nil {
+		return fmt.Errorf("failed to get logs: %v", err)
+	}
+	logs, err := decodeEthLogs(res)
+	if err != nil {
+		return err
+	}
+
+	for _, spec := range logsFilterSpecs(rangeSpec, logs) {
+		if err := ac.sendAndWait(ethGetLogs, toInterface(ac.ctx, spec)); err != nil {
+			return fmt.Errorf("address %v, topics %v, error: %w", spec.Address, spec.Topics, err)
+		}
+	}
+
+	return nil
+}
+
+// CompareEthNewFilter installs the same event filter on both nodes, then compares the logs, changes and uninstall result.
+func (ac *apiCompare) CompareEthNewFilter() error {
+	spec, err := ac.logsRangeSpec()
+	if err != nil {
+		return err
+	}
+
+	return ac.compareFilter(ethNewFilter, toInterface(ac.ctx, spec), func(vID types.EthFilterID, lID ethtypes.EthFilterID) error {
+		if err := ac.sendAndWait(ethGetFilterLogs, toInterface(ac.ctx, vID), withLotusParams(ac.ctx, lID)); err != nil {
+			return err
+		}
+		return ac.sendAndWait(ethGetFilterChanges, toInterface(ac.ctx, vID), withLotusParams(ac.ctx, lID))
+	})
+}
+
+// CompareEthNewBlockFilter compares the new blocks since the filter installed, the latest block may only arrive at one node.
+func (ac *apiCompare) CompareEthNewBlockFilter() error {
+	return ac.compareFilter(ethNewBlockFilter, toInterface(ac.ctx), func(vID types.EthFilterID, lID ethtypes.EthFilterID) error {
+		ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(chainHead))
+		head, err := ac.vAPI.ChainHead(ctx)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to get head: %w", err)
+		}
+		// the filter only has changes after new blocks arrived
+		if err := ac.waitNewHead(head.Height()); err != nil {
+			return err
+		}
+
+		return ac.sendAndWait(ethGetFilterChanges, toInterface(ac.ctx, vID), withLotusParams(ac.ctx, lID), withResultCheck(func(r1, r2 interface{}) error {
+			o1, _ := r1.(*types.EthFilterResult)
+			o2, _ := r2.(*ethtypes.EthFilterResult)
+			if (o1 == nil || len(o1.Results) == 0) && (o2 == nil || len(o2.Results) == 0) {
+				return fmt.Errorf("no new block after height %d", head.Height())
+			}
+			return checkFilterHashes(o1, o2)
+		}))
+	})
+}
+
+// CompareEthNewPendingTransactionFilter only checks the calls succeed on both nodes, because mempools naturally differ.
+func (ac *apiCompare) CompareEthNewPendingTransactionFilter() error {
+	return ac.compareFilter(ethNewPendingTransactionFilter, toInterface(ac.ctx), func(vID types.EthFilterID, lID ethtypes.EthFilterID) error {
+		return ac.sendAndWait(ethGetFilterChanges, toInterface(ac.ctx, vID), withLotusParams(ac.ctx, lID), withResultCheck(func(r1, r2 interface{}) error {
+			o1, _ := r1.(*types.EthFilterResult)
+			o2, _ := r2.(*ethtypes.EthFilterResult)
+			if o1 != nil && o2 != nil {
+				logrus.Debugf("pending transaction filter changes venus %d, lotus %d", len(o1.Results), len(o2.Results))
+			}
+			return nil
+		}))
+	})
+}
+
+// compareFilter installs filter by method on both nodes, runs f with the filter ids, and always uninstalls the filters
+// and compares the results. If the filter only installed on one node or the install timeout, uninstalls the installed
+// filter from that node, including the one installed after timeout.
+func (ac *apiCompare) compareFilter(method string, in []interface{}, f func(vID types.EthFilterID, lID ethtypes.EthFilterID) error) error {
+	var (
+		lk       sync.Mutex
+		finished bool
+		vID      types.EthFilterID
+		lID      ethtypes.EthFilterID
+	)
+	// the ids are needed to uninstall the filter even if only one node succeeded
+	onResult := func(node string, res interface{}) {
+		lk.Lock()
+		defer lk.Unlock()
+
+		switch id := res.(type) {
+		case types.EthFilterID:
+			vID = id
+		case ethtypes.EthFilterID:
+			lID = id
+		}
+		// the node answered after compareFilter gave up
+		if finished {
+			ac.uninstallFilter(res)
+		}
+	}
+	err := ac.sendAndWait(method, in, withExpectCallAPIError(), withResultNotify(onResult), withResultCheck(func(r1, r2 interface{}) error {
+		return nil
+	}))
+
+	lk.Lock()
+	finished = true
+	vInstalled := vID != types.EthFilterID{}
+	lInstalled := lID != ethtypes.EthFilterID{}
+	lk.Unlock()
+
+	if err != nil || !vInstalled || !lInstalled {
+		if vInstalled {
+			ac.uninstallFilter(vID)
+		}
+		if lInstalled {
+			ac.uninstallFilter(lID)
+		}
+		if err != nil {
+			return err
+		}
+		if vInstalled || lInstalled {
+			return fmt.Errorf("filter installed on venus %v, on lotus %v", vInstalled, lInstalled)
+		}
+		return fmt.Errorf("venus and lotus all failed to install filter")
+	}
+
+	err = f(vID, lID)
+	if uErr := ac.sendAndWait(ethUninstallFilter, toInterface(ac.ctx, vID), withLotusParams(ac.ctx, lID)); uErr != nil && err == nil {
+		err = fmt.Errorf("uninstall filter error: %w", uErr)
+	}
+
+	return err
+}
+
+// uninstallFilter uninstalls the filter installed on only one node, id is a venus or lotus filter id.
+func (ac *apiCompare) uninstallFilter(id interface{}) {
+	ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(ethUninstallFilter))
+	defer cancel()
+
+	var err error
+	switch id := id.(type) {
+	case types.EthFilterID:
+		_, err = ac.vAPI.EthUninstallFilter(ctx, id)
+	case ethtypes.EthFilterID:
+		_, err = ac.lAPI.EthUninstallFilter(ctx, id)
+	}
+	if err != nil {
+		logrus.Warnf("uninstall filter %v failed: %v", id, err)
+	}
+}
+
+// logsRangeSpec returns a filter spec of the ethLogsRange epochs ending at current tipset.
+func (ac *apiCompare) logsRangeSpec() (*types.EthFilterSpec, error) {
+	to, err := ac.dp.getBlkOptByHeight()
+	if err != nil {
+		return nil, err
+	}
+	start := ac.dp.currentTS.Height() - ethLogsRange + 1
+	if start < 0 {
+		start = 0
+	}
+	from := toBlkParam(start)
+
+	return &types.EthFilterSpec{FromBlock: &from, ToBlock: &to}, nil
+}
+
+func toBlkParam(h abi.ChainEpoch) string {
+	return types.EthUint64(h).Hex()
+}
+
+// logsFilterSpecs builds filters from logs, by address, by first topic, and by address with all topics.
+func logsFilterSpecs(base *types.EthFilterSpec, logs []types.EthLog) []*types.EthFilterSpec {
+	newSpec := func() *types.EthFilterSpec {
+		return &types.EthFilterSpec{FromBlock: base.FromBlock, ToBlock: base.ToBlock}
+	}
+
+	var specs []*types.EthFilterSpec
+	addrs := make(map[types.EthAddress]struct{})
+	topics := make(map[types.EthHash]struct{})
+	for _, log := range logs {
+		if _, ok := addrs[log.Address]; !ok && len(addrs) < ethLogsFilterSample {
+			addrs[log.Address] = struct{}{}
+			spec := newSpec()
+			spec.Address = types.EthAddressList{log.Address}
+			specs = append(specs, spec)
+		}
+		if len(log.Topics) == 0 {
+			continue
+		}
+		if _, ok := topics[log.Topics[0]]; !ok && len(topics) < ethLogsFilterSample {
+			topics[log.Topics[0]] = struct{}{}
+			spec := newSpec()
+			spec.Topics = types.EthTopicSpec{{log.Topics[0]}}
+			specs = append(specs, spec)
+		}
+	}
+
+	if len(logs) > 0 && len(logs[0].Topics) > 0 {
+		spec := newSpec()
+		spec.Address = types.EthAddressList{logs[0].Address}
+		for _, topic := range logs[0].Topics {
+			spec.Topics = append(spec.Topics, types.EthHashList{topic})
+		}
+		specs = append(specs, spec)
+	}
+
+	return specs
+}
+
+func decodeEthLogs(res *types.EthFilterResult) ([]types.EthLog, error) {
+	if res == nil || len(res.Results) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(res.Results)
+	if err != nil {
+		return nil, err
+	}
+	var logs []types.EthLog
+	if err := json.Unmarshal(data, &logs); err != nil {
+		return nil, fmt.Errorf("decode logs failed: %v", err)
+	}
+
+	return logs, nil
+}
+
+// checkFilterHashes checks the hashes are the same, one node may have one more hash at the end,
+// because a new block may arrive between the two calls.
+func checkFilterHashes(vRes *types.EthFilterResult, lRes *ethtypes.EthFilterResult) error {
+	var vHashes, lHashes []interface{}
+	if vRes != nil {
+		vHashes = vRes.Results
+	}
+	if lRes != nil {
+		lHashes = lRes.Results
+	}
+
+	n := len(vHashes)
+	if len(lHashes) < n {
+		n = len(lHashes)
+	}
+	if len(vHashes)-n > 1 || len(lHashes)-n > 1 {
+		return fmt.Errorf("hashes length %d != %d", len(vHashes), len(lHashes))
+	}
+	for i := 0; i < n; i++ {
+		if fmt.Sprint(vHashes[i]) != fmt.Sprint(lHashes[i]) {
+			return fmt.Errorf("hash %d %v != %v", i, vHashes[i], lHashes[i])
+		}
+	}
+
+	return nil
+}
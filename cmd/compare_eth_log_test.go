@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/lotus/chain/types/ethtypes"
+	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogsFilterSpecs(t *testing.T) {
+	from, to := "0x1", "0xa"
+	base := &types.EthFilterSpec{FromBlock: &from, ToBlock: &to}
+
+	assert.Len(t, logsFilterSpecs(base, nil), 0)
+
+	addr := types.EthAddress{1}
+	addr2 := types.EthAddress{2}
+	topic := types.EthHash{1}
+	topic2 := types.EthHash{2}
+	logs := []types.EthLog{
+		{Address: addr, Topics: []types.EthHash{topic, topic2}},
+		{Address: addr, Topics: []types.EthHash{topic2}},
+		{Address: addr2},
+	}
+
+	specs := logsFilterSpecs(base, logs)
+	// 2 addresses, 2 first topics, 1 address with all topics
+	assert.Len(t, specs, 5)
+	for _, spec := range specs {
+		assert.Equal(t, base.FromBlock, spec.FromBlock)
+		assert.Equal(t, base.ToBlock, spec.ToBlock)
+	}
+	last := specs[len(specs)-1]
+	assert.Equal(t, types.EthAddressList{addr}, last.Address)
+	assert.Equal(t, types.EthTopicSpec{{topic}, {topic2}}, last.Topics)
+}
+
+func TestCheckFilterHashes(t *testing.T) {
+	vRes := &types.EthFilterResult{Results: []interface{}{"0x01", "0x02"}}
+
+	assert.NoError(t, checkFilterHashes(vRes, &ethtypes.EthFilterResult{Results: []interface{}{"0x01", "0x02"}}))
+	assert.NoError(t, checkFilterHashes(vRes, &ethtypes.EthFilterResult{Results: []interface{}{"0x01"}}))
+	assert.NoError(t, checkFilterHashes(vRes, &ethtypes.EthFilterResult{Results: []interface{}{"0x01", "0x02", "0x03"}}))
+	assert.NoError(t, checkFilterHashes(nil, nil))
+	assert.Error(t, checkFilterHashes(vRes, &ethtypes.EthFilterResult{Results: []interface{}{"0x02", "0x01"}}))
+	assert.Error(t, checkFilterHashes(vRes, &ethtypes.EthFilterResult{}))
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+
+	ltypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/venus/venus-shared/types"
+)
+
+func (ac *apiCompare) CompareChainGetEvents() error {
+	for _, root := range ac.dp.getEventsRoots() {
+		if err := ac.sendAndWait(chainGetEvents, toInterface(ac.ctx, root), withResultCheck(checkEventsResult)); err != nil {
+			return fmt.Errorf("events root %s, error: %w", root, err)
+		}
+	}
+
+	return nil
+}
+
+func checkEventsResult(r1, r2 interface{}) error {
+	o1, _ := r1.([]types.Event)
+	o2, _ := r2.([]ltypes.Event)
+	return checkEvents(o1, o2)
+}
+
+// checkEvents compares events one by one, reports the first different event and entry.
+func checkEvents(vEvents []types.Event, lEvents []ltypes.Event) error {
+	if len(vEvents) != len(lEvents) {
+		return fmt.Errorf("events length %d != %d", len(vEvents), len(lEvents))
+	}
+
+	for i, event := range vEvents {
+		lEvent := lEvents[i]
+		if event.Emitter != lEvent.Emitter {
+			return fmt.Errorf("event %d emitter %d != %d", i, event.Emitter, lEvent.Emitter)
+		}
+		if len(event.Entries) != len(lEvent.Entries) {
+			return fmt.Errorf("event %d entries length %d != %d", i, len(event.Entries), len(lEvent.Entries))
+		}
+		for j, entry := range event.Entries {
+			lEntry := lEvent.Entries[j]
+			if entry.Flags != lEntry.Flags {
+				return fmt.Errorf("event %d entry %d flags %d != %d", i, j, entry.Flags, lEntry.Flags)
+			}
+			if entry.Key != lEntry.Key {
+				return fmt.Errorf("event %d entry %d key %s != %s", i, j, entry.Key, lEntry.Key)
+			}
+			if entry.Codec != lEntry.Codec {
+				return fmt.Errorf("event %d entry %d codec %d != %d", i, j, entry.Codec, lEntry.Codec)
+			}
+			if !bytes.Equal(entry.Value, lEntry.Value) {
+				return fmt.Errorf("event %d entry %d value %x != %x", i, j, entry.Value, lEntry.Value)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"testing"
+
+	ltypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckEvents(t *testing.T) {
+	build := func() ([]types.Event, []ltypes.Event) {
+		vEvents := []types.Event{{
+			Emitter: 1000,
+			Entries: []types.EventEntry{{Flags: 3, Key: "t1", Codec: 0x55, Value: []byte{1, 2}}},
+		}}
+		lEvents := []ltypes.Event{{
+			Emitter: 1000,
+			Entries: []ltypes.EventEntry{{Flags: 3, Key: "t1", Codec: 0x55, Value: []byte{1, 2}}},
+		}}
+		return vEvents, lEvents
+	}
+
+	vEvents, lEvents := build()
+	assert.NoError(t, checkEvents(vEvents, lEvents))
+	assert.NoError(t, checkEvents(nil, nil))
+
+	vEvents, lEvents = build()
+	lEvents[0].Emitter = 1001
+	assert.Error(t, checkEvents(vEvents, lEvents))
+
+	vEvents, lEvents = build()
+	lEvents[0].Entries[0].Flags = 1
+	assert.Error(t, checkEvents(vEvents, lEvents))
+
+	vEvents, lEvents = build()
+	lEvents[0].Entries[0].Codec = 0x71
+	assert.Error(t, checkEvents(vEvents, lEvents))
+
+	vEvents, lEvents = build()
+	lEvents[0].Entries[0].Value = []byte{2, 1}
+	assert.Error(t, checkEvents(vEvents, lEvents))
+
+	vEvents, lEvents = build()
+	lEvents = append(lEvents, lEvents[0])
+	assert.Error(t, checkEvents(vEvents, lEvents))
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/filecoin-project/go-state-types/big"
+	ltypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/venus/venus-shared/types"
+)
+
+const (
+	// the number of messages to estimate
+	gasEstimateMsgs = 5
+
+	maxQueueBlks = 10
+	nBlocksIncl  = 10
+)
+
+// estimateMsgs returns the messages of current tipset with gas fields cleared, they are evaluated
+// against current tipset, so the result not depends on the mempool as much as possible.
+func (ac *apiCompare) estimateMsgs() []*types.Message {
+	msgs := ac.dp.getMsgs()
+	if len(msgs) > gasEstimateMsgs {
+		msgs = msgs[:gasEstimateMsgs]
+	}
+
+	out := make([]*types.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		m := *msg
+		m.GasLimit = 0
+		m.GasFeeCap = big.Zero()
+		m.GasPremium = big.Zero()
+		out = append(out, &m)
+	}
+
+	return out
+}
+
+func (ac *apiCompare) checkFee(r1, r2 interface{}) error {
+	o1, _ := r1.(big.Int)
+	o2, _ := r2.(big.Int)
+	return bigIntWithinTolerance(o1, o2, ac.cfg.gasFeeTolerance)
+}
+
+func (ac *apiCompare) CompareGasEstimateMessageGas() error {
+	key := ac.dp.currentTS.Key()
+	for _, msg := range ac.estimateMsgs() {
+		err := ac.sendAndWait(gasEstimateMessageGas, toInterface(ac.ctx, msg, (*types.MessageSendSpec)(nil), key), withResultCheck(func(r1, r2 interface{}) error {
+			m1, _ := r1.(*types.Message)
+			m2, _ := r2.(*ltypes.Message)
+			if m1 == nil || m2 == nil {
+				return fmt.Errorf("one is nil %v %v", m1 == nil, m2 == nil)
+			}
+			if m1.GasLimit != m2.GasLimit {
+				return fmt.Errorf("gas limit not match %d != %d", m1.GasLimit, m2.GasLimit)
+			}
+			if err := bigIntWithinTolerance(m1.GasFeeCap, m2.GasFeeCap, ac.cfg.gasFeeTolerance); err != nil {
+				return fmt.Errorf("gas fee cap %w", err)
+			}
+			if err := bigIntWithinTolerance(m1.GasPremium, m2.GasPremium, ac.cfg.gasFeeTolerance); err != nil {
+				return fmt.Errorf("gas premium %w", err)
+			}
+			if m1.Nonce != m2.Nonce {
+				return fmt.Errorf("nonce not match %d != %d", m1.Nonce, m2.Nonce)
+			}
+
+			return nil
+		}))
+		if err != nil {
+			return fmt.Errorf("msg %s, error: %w", msg.Cid(), err)
+		}
+	}
+
+	return nil
+}
+
+func (ac *apiCompare) CompareGasEstimateGasLimit() error {
+	key := ac.dp.currentTS.Key()
+	for _, msg := range ac.estimateMsgs() {
+		if err := ac.sendAndWait(gasEstimateGasLimit, toInterface(ac.ctx, msg, key)); err != nil {
+			return fmt.Errorf("msg %s, error: %w", msg.Cid(), err)
+		}
+	}
+
+	return nil
+}
+
+func (ac *apiCompare) CompareGasEstimateFeeCap() error {
+	key := ac.dp.currentTS.Key()
+	for _, msg := range ac.estimateMsgs() {
+		if err := ac.sendAndWait(gasEstimateFeeCap, toInterface(ac.ctx, msg, int64(maxQueueBlks), key), withResultCheck(ac.checkFee)); err != nil {
+			return fmt.Errorf("msg %s, error: %w", msg.Cid(), err)
+		}
+	}
+
+	return nil
+}
+
+// the result only depends on the sender and gas limit, one message is enough
+func (ac *apiCompare) CompareGasEstimateGasPremium() error {
+	msg := ac.dp.getMsg()
+	if msg == nil {
+		return nil
+	}
+
+	return ac.sendAndWait(gasEstimateGasPremium, toInterface(ac.ctx, uint64(nBlocksIncl), msg.From, msg.GasLimit, ac.dp.currentTS.Key()),
+		withResultCheck(ac.checkFee))
+}
@@ -2,9 +2,11 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/filecoin-project/go-state-types/abi"
@@ -21,6 +23,7 @@ func newCompareMgr(ctx context.Context,
 	dp *dataProvider,
 	r *register,
 	currentTS *types.TipSet,
+	cfg *mgrConfig,
 ) *compareMgr {
 	mgr := &compareMgr{
 		ctx:       ctx,
@@ -29,12 +32,27 @@ func newCompareMgr(ctx context.Context,
 		dp:        dp,
 		currentTS: currentTS,
 		register:  r,
+		cfg:       cfg,
+		reorg:     newReorgTracker(),
 		next:      make(chan struct{}, 10),
+		reverted:  make(chan struct{}, 1),
 	}
 
 	return mgr
 }
 
+type mgrConfig struct {
+	// stateFile used to persist the last compared height
+	stateFile string
+	// resume compare from currentTS and catch up to the head one by one
+	resume bool
+	// policy and maxLag decide how to catch up when lagging behind the head
+	policy catchUpPolicy
+	maxLag int
+	// bounds the calls made to the nodes by compareMgr
+	timeouts *callTimeouts
+}
+
 type compareMgr struct {
 	ctx context.Context
 
@@ -43,78 +61,227 @@ type compareMgr struct {
 
 	dp       *dataProvider
 	register *register
+	cfg      *mgrConfig
 
 	currentTS *types.TipSet
-
-	next chan struct{}
+	// the tipset of the last saved checkpoint
+	checkpointTS *types.TipSet
+	reorg        *reorgTracker
+	// head height of venus, updated by chain notify
+	head int64
+	// bumped when the connection to any node dropped, a round is not reliable if it changed during the round
+	generation int64
+	// 1 if the state trees of a fork are being diffed
+	diffing int32
+
+	next     chan struct{}
+	reverted chan struct{}
 }
 
 func (mgr *compareMgr) start() {
 	if err := mgr.chainNotify(); err != nil {
 		logrus.Fatalf("chain notify error: %v\n", err)
 	}
+	go mgr.lotusChainNotify()
+	go mgr.healthLoop()
 
-	compare := func(h abi.ChainEpoch) {
-		ts, err := mgr.findTSByHeight(h)
-		if err != nil {
-			logrus.Errorf("found ts failed %v error %v", h, err)
-			return
+	if mgr.cfg.resume {
+		mgr.compare(mgr.currentTS.Height())
+		if err := mgr.catchUp(); err != nil {
+			logrus.Errorf("catch up failed: %v", err)
 		}
-		mgr.currentTS = ts
-
-		if err := mgr.compareAPI(); err != nil {
-			logrus.Errorf("compare api error: %v", err)
+	} else {
+		h := mgr.currentTS.Height() - defaultConfidence
+		if h < 0 {
+			h = 0
 		}
+		mgr.compare(h)
 	}
 
-	h := mgr.currentTS.Height() - defaultConfidence
-	if h < 0 {
-		h = 0
-	}
-	compare(h)
-
 	for {
 		select {
 		case <-mgr.ctx.Done():
 			logrus.Warn("context done")
 			return
 		case <-mgr.next:
-			compare(mgr.currentTS.Height() + 1)
+			mgr.followHead()
+		case <-mgr.reverted:
+			mgr.recompareReverted()
 		}
 	}
 }
 
-func (mgr *compareMgr) chainNotify() error {
+// compare returns true if all methods were compared at height h.
+func (mgr *compareMgr) compare(h abi.ChainEpoch) bool {
+	ts, timeouts, ok := mgr.compareHeight(mgr.ctx, h)
+	if !ok {
+		return false
+	}
+	mgr.currentTS = ts
+
+	mgr.reorg.markCompared(ts)
+	// the mismatches are reported already, only the methods not finished need to be compared again when resuming
+	if timeouts == 0 {
+		mgr.checkpointTS = ts
+		mgr.saveCheckpoint(ts)
+	} else {
+		logrus.Warnf("%d methods timeout at height %d, not save checkpoint", timeouts, h)
+	}
+
+	return true
+}
+
+// compareHeight compares all methods at height h, compares again if node restarted during comparing,
+// returns the number of methods timeout. waitCtx bounds the time waiting for nodes to converge.
+func (mgr *compareMgr) compareHeight(waitCtx context.Context, h abi.ChainEpoch) (*types.TipSet, int, bool) {
+	for {
+		if err := mgr.waitNodesReady(); err != nil {
+			logrus.Errorf("wait nodes ready failed: %v", err)
+			return nil, 0, false
+		}
+
+		ts, err := mgr.findTSByHeight(waitCtx, h)
+		if err != nil {
+			var fErr *forkError
+			if errors.As(err, &fErr) {
+				logrus.Errorf("%v", fErr)
+				mgr.diffForkState(fErr)
+			} else {
+				logrus.Errorf("found ts failed %v error %v", h, err)
+			}
+			return nil, 0, false
+		}
+
+		gen := atomic.LoadInt64(&mgr.generation)
+		timeouts, err := mgr.compareAPI(ts)
+		if err != nil {
+			logrus.Errorf("compare api error: %v", err)
+			return nil, 0, false
+		}
+
+		// the result is not reliable if node restarted during comparing, compare this height again
+		if err := mgr.checkHealth(); err != nil {
+			logrus.Warnf("%v, will compare height %d again", err, h)
+			continue
+		}
+		if atomic.LoadInt64(&mgr.generation) != gen {
+			logrus.Warnf("node reconnected during comparing, will compare height %d again", h)
+			continue
+		}
+
+		return ts, timeouts, true
+	}
+}
+
+// catchUp compares the skipped heights one by one until reach the head.
+func (mgr *compareMgr) catchUp() error {
+	for {
+		ctx, cancel := context.WithTimeout(mgr.ctx, mgr.cfg.timeouts.get(chainHead))
+		head, err := mgr.vAPI.ChainHead(ctx)
+		cancel()
+		if err != nil {
+			return err
+		}
+		target := head.Height() - defaultConfidence
+		if mgr.currentTS.Height() >= target {
+			logrus.Infof("caught up at height %d", mgr.currentTS.Height())
+			return nil
+		}
+		logrus.Infof("catching up, height %d, target %d", mgr.currentTS.Height(), target)
+
+		if !mgr.compare(mgr.currentTS.Height() + 1) {
+			select {
+			case <-mgr.ctx.Done():
+				return mgr.ctx.Err()
+			case <-time.After(reconnectMinDelay):
+			}
+		}
+	}
+}
+
+func (mgr *compareMgr) saveCheckpoint(ts *types.TipSet) {
+	if len(mgr.cfg.stateFile) == 0 {
+		return
+	}
+	cp := &checkpoint{
+		Height:    ts.Height(),
+		TipSetKey: ts.Key(),
+	}
+	if err := saveCheckpoint(mgr.cfg.stateFile, cp); err != nil {
+		logrus.Errorf("save checkpoint failed: %v", err)
+	}
+}
+
+func (mgr *compareMgr) notifyNext() {
+	select {
+	case mgr.next <- struct{}{}:
+	default:
+	}
+}
+
+func (mgr *compareMgr) subscribe() (<-chan []*types.HeadChange, error) {
 	notifies, err := mgr.vAPI.ChainNotify(mgr.ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	select {
-	case notify := <-notifies:
+	case notify, ok := <-notifies:
+		if !ok {
+			return nil, fmt.Errorf("chain notify channel closed")
+		}
 		if len(notify) != 1 {
-			return fmt.Errorf("expect hccurrent length 1 but for %d", len(notify))
+			return nil, fmt.Errorf("expect hccurrent length 1 but for %d", len(notify))
 		}
 
 		if notify[0].Type != types.HCCurrent {
-			return fmt.Errorf("expect hccurrent event but got %s ", notify[0].Type)
+			return nil, fmt.Errorf("expect hccurrent event but got %s ", notify[0].Type)
 		}
+		mgr.onHeadChange(notify[0].Val)
 	case <-mgr.ctx.Done():
-		return mgr.ctx.Err()
+		return nil, mgr.ctx.Err()
 	}
 
-	go func() {
-		for notify := range notifies {
-			var apply []*types.TipSet
+	return notifies, nil
+}
+
+func (mgr *compareMgr) chainNotify() error {
+	notifies, err := mgr.subscribe()
+	if err != nil {
+		return err
+	}
 
-			for _, change := range notify {
-				switch change.Type {
-				case types.HCApply:
-					apply = append(apply, change.Val)
+	go func() {
+		for {
+			for notify := range notifies {
+				var apply []*types.TipSet
+
+				for _, change := range notify {
+					switch change.Type {
+					case types.HCRevert:
+						mgr.onRevert("venus", change.Val.Height(), change.Val.Key())
+					case types.HCApply:
+						apply = append(apply, change.Val)
+					}
+				}
+				if len(apply) == 0 {
+					continue
 				}
+				// the last applied tipset is the new head
+				mgr.onHeadChange(apply[len(apply)-1])
 			}
-			if apply[0].Height() > (mgr.currentTS.Height() + defaultConfidence) {
-				mgr.next <- struct{}{}
+
+			// the channel is closed when the connection drops, subscribe again after reconnected
+			logrus.Warn("chain notify channel closed, resubscribe")
+			mgr.onDisconnect()
+			err := retry(mgr.ctx, "resubscribe chain notify", func() error {
+				var err error
+				notifies, err = mgr.subscribe()
+				return err
+			})
+			if err != nil {
+				logrus.Warnf("stop chain notify: %v", err)
+				return
 			}
 		}
 	}()
@@ -122,38 +289,88 @@ func (mgr *compareMgr) chainNotify() error {
 	return nil
 }
 
-func (mgr *compareMgr) findTSByHeight(h abi.ChainEpoch) (*types.TipSet, error) {
-	vts, err := mgr.vAPI.ChainGetTipSetAfterHeight(mgr.ctx, h, types.EmptyTSK)
-	if err != nil {
-		return nil, err
+func (mgr *compareMgr) onHeadChange(head *types.TipSet) {
+	atomic.StoreInt64(&mgr.head, int64(head.Height()))
+	if head.Height() > (mgr.currentTS.Height() + defaultConfidence) {
+		mgr.notifyNext()
 	}
-	lts, err := mgr.lAPI.ChainGetTipSetAfterHeight(mgr.ctx, h, ltypes.EmptyTSK)
-	if err != nil {
-		return nil, err
+}
+
+// findTSByHeight returns the tipset both nodes agree on at height h, if they pick different tipsets,
+// wait for them to converge, and finally returns forkError.
+func (mgr *compareMgr) findTSByHeight(ctx context.Context, h abi.ChainEpoch) (*types.TipSet, error) {
+	for i := 0; ; i++ {
+		callCtx, cancel := context.WithTimeout(ctx, mgr.cfg.timeouts.get(chainGetTipSetAfterHeight))
+		vts, err := mgr.vAPI.ChainGetTipSetAfterHeight(callCtx, h, types.EmptyTSK)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		lts, err := mgr.lAPI.ChainGetTipSetAfterHeight(callCtx, h, ltypes.EmptyTSK)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+
+		lKey := types.NewTipSetKey(lts.Cids()...)
+		if vts.Height() == lts.Height() && vts.Key().Equals(lKey) {
+			return vts, nil
+		}
+
+		fErr := &forkError{
+			height:  h,
+			vHeight: vts.Height(),
+			lHeight: lts.Height(),
+			vKey:    vts.Key(),
+			lKey:    lKey,
+			vState:  vts.ParentState(),
+			lState:  lts.ParentState(),
+			// the parent states can only be compared when the tipsets are built on the same parent
+			sameParent: vts.Parents().Equals(types.NewTipSetKey(lts.Parents().Cids()...)),
+		}
+		if i >= forkWaitTimes {
+			return nil, fErr
+		}
+		logrus.Warnf("%v, wait for converge", fErr)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(forkWaitInterval):
+		}
 	}
+}
 
-	if vts.Height() != lts.Height() {
-		return nil, fmt.Errorf("height not match %d != %d", vts.Height(), lts.Height())
+// compareAPI returns the number of methods timeout.
+func (mgr *compareMgr) compareAPI(ts *types.TipSet) (int, error) {
+	if err := mgr.dp.reset(ts); err != nil {
+		return 0, err
 	}
-	if !vts.Key().Equals(types.NewTipSetKey(lts.Cids()...)) {
-		return nil, fmt.Errorf("key not match %v != %v", vts.Key(), lts.Key())
+	logrus.Infof("start compare %d methods, height %d", len(mgr.register.funcs), ts.Height())
+	start := time.Now()
+	failed, timeouts := mgr.runFuncs(mgr.register.funcs)
+	logrus.Infof("end compare methods took %v, %d failed, %d timeout, lag %d epochs\n\n", time.Since(start), failed,
+		timeouts, mgr.lag(ts.Height()))
+
+	if nullRounds := mgr.dp.getNullRounds(); len(nullRounds) > 0 {
+		logrus.Infof("start compare null rounds %v, height %d", nullRounds, ts.Height())
+		start := time.Now()
+		_, n := mgr.runFuncs(mgr.register.nullRoundFuncs)
+		timeouts += n
+		logrus.Infof("end compare null rounds took %v\n\n", time.Since(start))
 	}
 
-	return vts, nil
+	return timeouts, nil
 }
 
-func (mgr *compareMgr) compareAPI() error {
-	if err := mgr.dp.reset(mgr.currentTS); err != nil {
-		return err
-	}
-	logrus.Infof("start compare %d methods, height %d", len(mgr.register.funcs), mgr.currentTS.Height())
-
+// runFuncs returns the number of failed methods and how many of them timeout.
+func (mgr *compareMgr) runFuncs(funcs map[string]rf) (int, int) {
 	sorted := make([]struct {
 		name string
 		f    rf
-	}, 0, len(mgr.register.funcs))
+	}, 0, len(funcs))
 
-	for name, f := range mgr.register.funcs {
+	for name, f := range funcs {
 		sorted = append(sorted, struct {
 			name string
 			f    rf
@@ -166,7 +383,7 @@ func (mgr *compareMgr) compareAPI() error {
 		logrus.Debugf(v.name)
 	}
 
-	start := time.Now()
+	var failed, timeouts int32
 	wg := sync.WaitGroup{}
 	for _, v := range sorted {
 		wg.Add(1)
@@ -175,19 +392,31 @@ func (mgr *compareMgr) compareAPI() error {
 		f := v.f
 		go func() {
 			defer wg.Done()
-			mgr.printResult(name, f())
+			err := f()
+			if err != nil {
+				atomic.AddInt32(&failed, 1)
+			}
+			var tErr *timeoutError
+			if errors.As(err, &tErr) {
+				atomic.AddInt32(&timeouts, 1)
+			}
+			mgr.printResult(name, err)
 		}()
 
 	}
 	wg.Wait()
 
-	logrus.Infof("end compare methods took %v\n\n", time.Since(start))
-
-	return nil
+	return int(failed), int(timeouts)
 }
 
 func (mgr *compareMgr) printResult(method string, err error) {
-	if err != nil {
+	var tErr *timeoutError
+	var cErr *criticalError
+	if errors.As(err, &tErr) {
+		logrus.Errorf("compare %s timeout: %v \n", method, err)
+	} else if errors.As(err, &cErr) {
+		logrus.Errorf("[CRITICAL] compare %s failed: %v \n", method, err)
+	} else if err != nil {
 		logrus.Errorf("compare %s failed: %v \n", method, err)
 	} else {
 		logrus.Infof("compare %s success \n", method)
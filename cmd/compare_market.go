@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/builtin"
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/venus/venus-shared/types"
+)
+
+const (
+	// the number of deals to compare when not compare all deals
+	marketDealsSample = 20
+	// the number of allocations or claims to compare for each address
+	verifregSample = 5
+)
+
+func (ac *apiCompare) CompareStateMarketBalance() error {
+	key := ac.dp.currentTS.Key()
+	addrs := append(append([]address.Address{}, ac.dp.getDealClients()...), ac.dp.getDealProviders()...)
+
+	for _, addr := range addrs {
+		if err := ac.sendAndWait(stateMarketBalance, toInterface(ac.ctx, addr, key)); err != nil {
+			return fmt.Errorf("address %s, error: %w", addr, err)
+		}
+	}
+
+	return nil
+}
+
+// CompareStateMarketDeals compares all deals when fullMarketDeals is set, it is expensive on large networks,
+// otherwise only compare the deals sampled from the harvested and existing ones by StateMarketStorageDeal.
+func (ac *apiCompare) CompareStateMarketDeals() error {
+	key := ac.dp.currentTS.Key()
+
+	if ac.cfg.fullMarketDeals {
+		return ac.sendAndWait(stateMarketDeals, toInterface(ac.ctx, key), withResultCheck(func(r1, r2 interface{}) error {
+			o1, _ := r1.(map[string]*types.MarketDeal)
+			o2, _ := r2.(map[string]*api.MarketDeal)
+			return checkMarketDeals(o1, o2)
+		}))
+	}
+
+	nextID, err := ac.marketNextDealID(key)
+	if err != nil {
+		return err
+	}
+	for _, id := range sampleDealIDs(ac.dp.getDealIDs(), nextID, marketDealsSample) {
+		// the sampled deal may be expired or terminated
+		err := ac.sendAndWait(stateMarketStorageDeal, toInterface(ac.ctx, id, key), withErrorCheck(checkErrorPresence))
+		if err != nil {
+			return fmt.Errorf("deal %d, error: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// marketNextDealID returns the next deal id of the market actor, the deal ids below it had been allocated.
+func (ac *apiCompare) marketNextDealID(key types.TipSetKey) (abi.DealID, error) {
+	ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(stateReadState))
+	defer cancel()
+
+	st, err := ac.vAPI.StateReadState(ctx, builtin.StorageMarketActorAddr, key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read market state: %w", err)
+	}
+	m, _ := st.State.(map[string]interface{})
+	nextID, ok := m["NextID"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected market state %v", st.State)
+	}
+
+	return abi.DealID(nextID), nil
+}
+
+func (ac *apiCompare) CompareStateMarketStorageDeal() error {
+	key := ac.dp.currentTS.Key()
+	for _, id := range ac.dp.getDealIDs() {
+		if err := ac.sendAndWait(stateMarketStorageDeal, toInterface(ac.ctx, id, key), withExpectCallAPIError()); err != nil {
+			return fmt.Errorf("deal %d, error: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// CompareStateMarketParticipants also compares the total escrow and locked funds, which are economics data,
+// so it is marked as critical.
+func (ac *apiCompare) CompareStateMarketParticipants() error {
+	return ac.sendAndWait(stateMarketParticipants, toInterface(ac.ctx, ac.dp.currentTS.Key()), withCritical(), withResultCheck(func(r1, r2 interface{}) error {
+		o1, _ := r1.(map[string]types.MarketBalance)
+		o2, _ := r2.(map[string]api.MarketBalance)
+		if err := checkParticipantsTotal(o1, o2); err != nil {
+			return err
+		}
+		return checkByJSON(r1, r2)
+	}))
+}
+
+func (ac *apiCompare) CompareStateDealProviderCollateralBounds() error {
+	key := ac.dp.currentTS.Key()
+	sizes := []abi.PaddedPieceSize{2 << 10, 32 << 30, 64 << 30}
+
+	for _, size := range sizes {
+		for _, verified := range []bool{false, true} {
+			if err := ac.sendAndWait(stateDealProviderCollateralBounds, toInterface(ac.ctx, size, verified, key)); err != nil {
+				return fmt.Errorf("size %d, verified %v, error: %w", size, verified, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (ac *apiCompare) CompareStateGetAllocation() error {
+	key := ac.dp.currentTS.Key()
+	for _, client := range ac.dp.getDealClients() {
+		ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(stateGetAllocations))
+		allocations, err := ac.vAPI.StateGetAllocations(ctx, client, key)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to get allocations of %s: %w", client, err)
+		}
+		ids := make([]types.AllocationId, 0, len(allocations))
+		for id := range allocations {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			return ids[i] < ids[j]
+		})
+		if len(ids) > verifregSample {
+			ids = ids[:verifregSample]
+		}
+
+		for _, id := range ids {
+			if err := ac.sendAndWait(stateGetAllocation, toInterface(ac.ctx, client, id, key)); err != nil {
+				return fmt.Errorf("client %s, allocation %d, error: %w", client, id, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (ac *apiCompare) CompareStateGetClaim() error {
+	key := ac.dp.currentTS.Key()
+	for _, provider := range ac.dp.getDealProviders() {
+		ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(stateGetClaims))
+		claims, err := ac.vAPI.StateGetClaims(ctx, provider, key)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to get claims of %s: %w", provider, err)
+		}
+		ids := make([]types.ClaimId, 0, len(claims))
+		for id := range claims {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			return ids[i] < ids[j]
+		})
+		if len(ids) > verifregSample {
+			ids = ids[:verifregSample]
+		}
+
+		for _, id := range ids {
+			if err := ac.sendAndWait(stateGetClaim, toInterface(ac.ctx, provider, id, key)); err != nil {
+				return fmt.Errorf("provider %s, claim %d, error: %w", provider, id, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// sampleDealIDs returns the harvested deal ids and count ids spread evenly below nextID.
+func sampleDealIDs(harvested []abi.DealID, nextID abi.DealID, count int) []abi.DealID {
+	out := append(make([]abi.DealID, 0, len(harvested)+count), harvested...)
+	if nextID == 0 || count <= 0 {
+		return out
+	}
+	if abi.DealID(count) > nextID {
+		count = int(nextID)
+	}
+	step := nextID / abi.DealID(count)
+	for i := 0; i < count; i++ {
+		out = append(out, abi.DealID(i)*step)
+	}
+
+	return out
+}
+
+// checkMarketDeals compares all deals.
+func checkMarketDeals(vDeals map[string]*types.MarketDeal, lDeals map[string]*api.MarketDeal) error {
+	if len(vDeals) != len(lDeals) {
+		return fmt.Errorf("deals length %d != %d", len(vDeals), len(lDeals))
+	}
+	for id := range vDeals {
+		deal, vOK := vDeals[id]
+		lDeal, lOK := lDeals[id]
+		if vOK != lOK {
+			return fmt.Errorf("deal %s exist in venus %v, exist in lotus %v", id, vOK, lOK)
+		}
+		if err := checkByJSON(deal, lDeal); err != nil {
+			return fmt.Errorf("deal %s %w", id, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleDealIDs(t *testing.T) {
+	assert.Empty(t, sampleDealIDs(nil, 0, 10))
+	assert.Equal(t, []abi.DealID{7}, sampleDealIDs([]abi.DealID{7}, 0, 10))
+	assert.Equal(t, []abi.DealID{7, 0, 1, 2}, sampleDealIDs([]abi.DealID{7}, 3, 10))
+	assert.Equal(t, []abi.DealID{0, 50}, sampleDealIDs(nil, 100, 2))
+}
+
+func TestCheckMarketDeals(t *testing.T) {
+	vDeals := map[string]*types.MarketDeal{"1": {}, "2": {}}
+	lDeals := map[string]*api.MarketDeal{"1": {}, "2": {}}
+	assert.NoError(t, checkMarketDeals(vDeals, lDeals))
+
+	lDeals = map[string]*api.MarketDeal{"1": {}, "3": {}}
+	assert.Error(t, checkMarketDeals(vDeals, lDeals))
+
+	delete(lDeals, "3")
+	assert.Error(t, checkMarketDeals(vDeals, lDeals))
+}
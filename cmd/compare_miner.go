@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/venus/venus-shared/actors/builtin/miner"
+	"github.com/filecoin-project/venus/venus-shared/types"
+)
+
+// the number of sectors to compare in StateMinerSectors
+const minerSectorsLimit = 100
+
+func (ac *apiCompare) forEachMiner(f func(maddr address.Address) error) error {
+	for _, maddr := range ac.dp.getMiners() {
+		if err := f(maddr); err != nil {
+			return fmt.Errorf("miner %s, error: %w", maddr, err)
+		}
+	}
+
+	return nil
+}
+
+func (ac *apiCompare) CompareStateMinerInfo() error {
+	key := ac.dp.currentTS.Key()
+
+	return ac.forEachMiner(func(maddr address.Address) error {
+		return ac.sendAndWait(stateMinerInfo, toInterface(ac.ctx, maddr, key))
+	})
+}
+
+func (ac *apiCompare) CompareStateMinerPower() error {
+	key := ac.dp.currentTS.Key()
+
+	return ac.forEachMiner(func(maddr address.Address) error {
+		return ac.sendAndWait(stateMinerPower, toInterface(ac.ctx, maddr, key))
+	})
+}
+
+func (ac *apiCompare) CompareStateMinerSectors() error {
+	key := ac.dp.currentTS.Key()
+	sectorNos := make([]uint64, 0, minerSectorsLimit)
+	for i := uint64(0); i < minerSectorsLimit; i++ {
+		sectorNos = append(sectorNos, i)
+	}
+	filter := bitfield.NewFromSet(sectorNos)
+
+	return ac.forEachMiner(func(maddr address.Address) error {
+		return ac.sendAndWait(stateMinerSectors, toInterface(ac.ctx, maddr, &filter, key))
+	})
+}
+
+func (ac *apiCompare) CompareStateMinerActiveSectors() error {
+	key := ac.dp.currentTS.Key()
+
+	return ac.forEachMiner(func(maddr address.Address) error {
+		return ac.sendAndWait(stateMinerActiveSectors, toInterface(ac.ctx, maddr, key))
+	})
+}
+
+func (ac *apiCompare) CompareStateMinerFaults() error {
+	key := ac.dp.currentTS.Key()
+
+	return ac.forEachMiner(func(maddr address.Address) error {
+		return ac.sendAndWait(stateMinerFaults, toInterface(ac.ctx, maddr, key))
+	})
+}
+
+func (ac *apiCompare) CompareStateMinerRecoveries() error {
+	key := ac.dp.currentTS.Key()
+
+	return ac.forEachMiner(func(maddr address.Address) error {
+		return ac.sendAndWait(stateMinerRecoveries, toInterface(ac.ctx, maddr, key))
+	})
+}
+
+func (ac *apiCompare) CompareStateMinerDeadlines() error {
+	key := ac.dp.currentTS.Key()
+
+	return ac.forEachMiner(func(maddr address.Address) error {
+		return ac.sendAndWait(stateMinerDeadlines, toInterface(ac.ctx, maddr, key))
+	})
+}
+
+func (ac *apiCompare) CompareStateMinerPartitions() error {
+	key := ac.dp.currentTS.Key()
+
+	return ac.forEachMiner(func(maddr address.Address) error {
+		ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(stateMinerProvingDeadline))
+		defer cancel()
+
+		dl, err := ac.vAPI.StateMinerProvingDeadline(ctx, maddr, key)
+		if err != nil {
+			return fmt.Errorf("failed to get proving deadline: %w", err)
+		}
+
+		return ac.sendAndWait(stateMinerPartitions, toInterface(ac.ctx, maddr, dl.Index, key))
+	})
+}
+
+func (ac *apiCompare) CompareStateMinerProvingDeadline() error {
+	key := ac.dp.currentTS.Key()
+
+	return ac.forEachMiner(func(maddr address.Address) error {
+		return ac.sendAndWait(stateMinerProvingDeadline, toInterface(ac.ctx, maddr, key))
+	})
+}
+
+func (ac *apiCompare) CompareStateMinerAvailableBalance() error {
+	key := ac.dp.currentTS.Key()
+
+	return ac.forEachMiner(func(maddr address.Address) error {
+		return ac.sendAndWait(stateMinerAvailableBalance, toInterface(ac.ctx, maddr, key), withResultCheck(func(r1, r2 interface{}) error {
+			o1, _ := r1.(big.Int)
+			o2, _ := r2.(big.Int)
+			return bigIntEqual(&o1, &o2)
+		}))
+	})
+}
+
+func (ac *apiCompare) CompareStateMinerSectorCount() error {
+	key := ac.dp.currentTS.Key()
+
+	return ac.forEachMiner(func(maddr address.Address) error {
+		return ac.sendAndWait(stateMinerSectorCount, toInterface(ac.ctx, maddr, key))
+	})
+}
+
+func (ac *apiCompare) CompareStateMinerInitialPledgeCollateral() error {
+	ts := ac.dp.currentTS
+	key := ts.Key()
+	ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(stateNetworkVersion))
+	nv, err := ac.vAPI.StateNetworkVersion(ctx, key)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	return ac.forEachMiner(func(maddr address.Address) error {
+		ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(stateMinerInfo))
+		defer cancel()
+
+		info, err := ac.vAPI.StateMinerInfo(ctx, maddr, key)
+		if err != nil {
+			return fmt.Errorf("failed to get miner info: %w", err)
+		}
+		sealProof, err := miner.PreferredSealProofTypeFromWindowPoStType(nv, info.WindowPoStProofType)
+		if err != nil {
+			return err
+		}
+		pci := types.SectorPreCommitInfo{
+			SealProof:    sealProof,
+			SectorNumber: 0,
+			// only used to encode params, not affect the result
+			SealedCID:     ts.Blocks()[0].ParentStateRoot,
+			SealRandEpoch: ts.Height(),
+			// about 180 days
+			Expiration: ts.Height() + 180*2880,
+		}
+
+		return ac.sendAndWait(stateMinerInitialPledgeCollateral, toInterface(ac.ctx, maddr, pci, key), withResultCheck(func(r1, r2 interface{}) error {
+			o1, _ := r1.(big.Int)
+			o2, _ := r2.(big.Int)
+			return bigIntEqual(&o1, &o2)
+		}))
+	})
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/filecoin-project/go-address"
+	ltypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/venus/pkg/constants"
+	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/ipfs/go-cid"
+	"github.com/sirupsen/logrus"
+)
+
+// Mempools naturally differ between nodes, so the mpool apis are compared by structural properties
+// rather than equality.
+
+const ticketQuality = 0.8
+
+// CompareMpoolPending checks the pending messages present on both nodes are the same.
+func (ac *apiCompare) CompareMpoolPending() error {
+	return ac.sendAndWait(mpoolPending, toInterface(ac.ctx, types.EmptyTSK), withResultCheck(func(r1, r2 interface{}) error {
+		vMsgs, _ := r1.([]*types.SignedMessage)
+		lMsgs, _ := r2.([]*ltypes.SignedMessage)
+
+		lMap := make(map[cid.Cid]*ltypes.SignedMessage, len(lMsgs))
+		for _, msg := range lMsgs {
+			lMap[msg.Cid()] = msg
+		}
+		shared := 0
+		for _, msg := range vMsgs {
+			lMsg, ok := lMap[msg.Cid()]
+			if !ok {
+				continue
+			}
+			shared++
+			if err := checkByJSON(msg, lMsg); err != nil {
+				return fmt.Errorf("pending msg %s %w", msg.Cid(), err)
+			}
+		}
+		logrus.Debugf("pending messages venus %d, lotus %d, shared %d", len(vMsgs), len(lMsgs), shared)
+
+		return nil
+	}))
+}
+
+// CompareMpoolGetNonce compares nonce of the senders which have the same pending messages on both nodes.
+func (ac *apiCompare) CompareMpoolGetNonce() error {
+	ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(mpoolPending))
+	defer cancel()
+
+	vPending, err := ac.vAPI.MpoolPending(ctx, types.EmptyTSK)
+	if err != nil {
+		return fmt.Errorf("failed to get venus pending messages: %w", err)
+	}
+	lPending, err := ac.lAPI.MpoolPending(ctx, ltypes.EmptyTSK)
+	if err != nil {
+		return fmt.Errorf("failed to get lotus pending messages: %w", err)
+	}
+
+	vBySender := make(map[address.Address][]cid.Cid)
+	for _, msg := range vPending {
+		vBySender[msg.Message.From] = append(vBySender[msg.Message.From], msg.Cid())
+	}
+	lBySender := make(map[address.Address][]cid.Cid)
+	for _, msg := range lPending {
+		lBySender[msg.Message.From] = append(lBySender[msg.Message.From], msg.Cid())
+	}
+
+	senders := append([]address.Address{}, ac.dp.getSenders()...)
+	for addr := range vBySender {
+		if _, ok := lBySender[addr]; ok && !containAddress(senders, addr) {
+			senders = append(senders, addr)
+		}
+	}
+
+	for _, addr := range senders {
+		if !sameCids(vBySender[addr], lBySender[addr]) {
+			logrus.Debugf("skip compare nonce of %s, pending messages not match", addr)
+			continue
+		}
+		if err := ac.sendAndWait(mpoolGetNonce, toInterface(ac.ctx, addr)); err != nil {
+			return fmt.Errorf("address %s, error: %w", addr, err)
+		}
+	}
+
+	return nil
+}
+
+// CompareMpoolSelect checks the messages selected by each node are valid for a block.
+func (ac *apiCompare) CompareMpoolSelect() error {
+	return ac.sendAndWait(mpoolSelect, toInterface(ac.ctx, types.EmptyTSK, ticketQuality), withResultCheck(func(r1, r2 interface{}) error {
+		vMsgs, _ := r1.([]*types.SignedMessage)
+		lMsgs, err := unmarshalAny[[]*types.SignedMessage](r2)
+		if err != nil {
+			return err
+		}
+		if err := checkSelectedMsgs(vMsgs); err != nil {
+			return fmt.Errorf("venus %w", err)
+		}
+		if err := checkSelectedMsgs(lMsgs); err != nil {
+			return fmt.Errorf("lotus %w", err)
+		}
+
+		return nil
+	}))
+}
+
+// CompareMpoolGetConfig only prints the difference, the config is local to the node.
+func (ac *apiCompare) CompareMpoolGetConfig() error {
+	return ac.sendAndWait(mpoolGetConfig, toInterface(ac.ctx), withResultCheck(func(r1, r2 interface{}) error {
+		if err := checkByJSON(r1, r2); err != nil {
+			logrus.Infof("compare MpoolGetConfig: %v\n", err)
+		}
+		return nil
+	}))
+}
+
+// CompareMpoolCheckMessages checks both nodes give the same verdicts for the same messages,
+// error messages are not compared.
+func (ac *apiCompare) CompareMpoolCheckMessages() error {
+	msgs := ac.dp.getMsgs()
+	if len(msgs) == 0 {
+		return nil
+	}
+	protos := make([]*types.MessagePrototype, 0, len(msgs)*2)
+	for _, msg := range msgs {
+		// the messages are on chain, so the nonce is invalid unless ValidNonce is set
+		protos = append(protos, &types.MessagePrototype{Message: *msg, ValidNonce: true})
+		protos = append(protos, &types.MessagePrototype{Message: *msg})
+	}
+
+	return ac.sendAndWait(mpoolCheckMessages, toInterface(ac.ctx, protos), withResultCheck(func(r1, r2 interface{}) error {
+		v1, err := unmarshalAny[[][]checkVerdict](r1)
+		if err != nil {
+			return err
+		}
+		v2, err := unmarshalAny[[][]checkVerdict](r2)
+		if err != nil {
+			return err
+		}
+
+		return checkByJSON(v1, v2)
+	}))
+}
+
+type checkVerdict struct {
+	Cid  cid.Cid
+	Code int
+	OK   bool
+}
+
+func sameCids(a, b []cid.Cid) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[cid.Cid]struct{}, len(a))
+	for _, c := range a {
+		set[c] = struct{}{}
+	}
+	for _, c := range b {
+		if _, ok := set[c]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// checkSelectedMsgs checks nonce of each sender is continuous and total gas limit not exceed block gas limit.
+func checkSelectedMsgs(msgs []*types.SignedMessage) error {
+	var gasLimit int64
+	nonces := make(map[address.Address][]uint64)
+	for _, msg := range msgs {
+		gasLimit += msg.Message.GasLimit
+		nonces[msg.Message.From] = append(nonces[msg.Message.From], msg.Message.Nonce)
+	}
+	if gasLimit > constants.BlockGasLimit {
+		return fmt.Errorf("total gas limit %d exceed block gas limit %d", gasLimit, constants.BlockGasLimit)
+	}
+
+	for addr, list := range nonces {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i] < list[j]
+		})
+		for i := 1; i < len(list); i++ {
+			if list[i] != list[i-1]+1 {
+				return fmt.Errorf("nonce of %s not continuous %v", addr, list)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSelectedMsgs(t *testing.T) {
+	from, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+
+	newMsg := func(nonce uint64, gasLimit int64) *types.SignedMessage {
+		return &types.SignedMessage{Message: types.Message{From: from, Nonce: nonce, GasLimit: gasLimit}}
+	}
+
+	assert.NoError(t, checkSelectedMsgs([]*types.SignedMessage{newMsg(2, 100), newMsg(1, 100)}))
+	assert.Error(t, checkSelectedMsgs([]*types.SignedMessage{newMsg(1, 100), newMsg(3, 100)}))
+	assert.Error(t, checkSelectedMsgs([]*types.SignedMessage{newMsg(1, 6_000_000_000), newMsg(2, 6_000_000_000)}))
+}
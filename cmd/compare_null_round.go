@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	ltypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/ethtypes"
+	"github.com/filecoin-project/venus/venus-shared/types"
+)
+
+// The apis may return an error, the previous tipset or the next tipset at null rounds, the methods below
+// compare which one venus and lotus return at every null round before current tipset.
+
+const (
+	outcomeError    = "error"
+	outcomePrevious = "previous tipset"
+	outcomeNext     = "next tipset"
+)
+
+func (ac *apiCompare) NullRoundEthGetBlockByNumber() error {
+	prevHash, nextHash, err := ac.nullRoundBlockHashes()
+	if err != nil {
+		return err
+	}
+	outcome := func(hash types.EthHash) string {
+		switch hash {
+		case emptyEthHash:
+			return outcomeError
+		case prevHash:
+			return outcomePrevious
+		case nextHash:
+			return outcomeNext
+		}
+		return fmt.Sprintf("block %s", hash)
+	}
+
+	return ac.forEachNullRound(func(h abi.ChainEpoch) error {
+		for _, fullTxInfo := range []bool{false, true} {
+			err := ac.sendAndWait(ethGetBlockByNumber, toInterface(ac.ctx, toBlkParam(h), fullTxInfo), withExpectCallAPIError(),
+				withResultCheck(func(r1, r2 interface{}) error {
+					o1, _ := r1.(types.EthBlock)
+					o2, _ := r2.(ethtypes.EthBlock)
+					return checkOutcome(outcome(o1.Hash), outcome(types.EthHash(o2.Hash)))
+				}))
+			if err != nil {
+				return fmt.Errorf("fullTxInfo %v, error: %w", fullTxInfo, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (ac *apiCompare) NullRoundChainGetTipSetByHeight() error {
+	key := ac.dp.currentTS.Key()
+
+	return ac.forEachNullRound(func(h abi.ChainEpoch) error {
+		return ac.sendAndWait(chainGetTipSetByHeight, toInterface(ac.ctx, h, key), withExpectCallAPIError(),
+			withResultCheck(ac.checkTipSetOutcome))
+	})
+}
+
+func (ac *apiCompare) NullRoundChainGetTipSetAfterHeight() error {
+	key := ac.dp.currentTS.Key()
+
+	return ac.forEachNullRound(func(h abi.ChainEpoch) error {
+		return ac.sendAndWait(chainGetTipSetAfterHeight, toInterface(ac.ctx, h, key), withExpectCallAPIError(),
+			withResultCheck(ac.checkTipSetOutcome))
+	})
+}
+
+func (ac *apiCompare) NullRoundEthFeeHistory() error {
+	prev, next := ac.dp.getParent().Height(), ac.dp.currentTS.Height()
+	outcome := func(oldest uint64, hasData bool) string {
+		switch {
+		case !hasData:
+			return outcomeError
+		case oldest == uint64(prev):
+			return outcomePrevious
+		case oldest == uint64(next):
+			return outcomeNext
+		}
+		return fmt.Sprintf("oldest block %d", oldest)
+	}
+
+	return ac.forEachNullRound(func(h abi.ChainEpoch) error {
+		params, err := json.Marshal(types.EthFeeHistoryParams{NewestBlkNum: toBlkParam(h), BlkCount: 1})
+		if err != nil {
+			return err
+		}
+
+		return ac.sendAndWait(ethFeeHistory, toInterface(ac.ctx, params), withExpectCallAPIError(),
+			withResultCheck(func(r1, r2 interface{}) error {
+				o1, _ := r1.(types.EthFeeHistory)
+				o2, _ := r2.(ethtypes.EthFeeHistory)
+				return checkOutcome(outcome(uint64(o1.OldestBlock), o1.BaseFeePerGas != nil),
+					outcome(uint64(o2.OldestBlock), o2.BaseFeePerGas != nil))
+			}))
+	})
+}
+
+func (ac *apiCompare) forEachNullRound(f func(h abi.ChainEpoch) error) error {
+	for _, h := range ac.dp.getNullRounds() {
+		if err := f(h); err != nil {
+			return fmt.Errorf("null round %d, error: %w", h, err)
+		}
+	}
+
+	return nil
+}
+
+func (ac *apiCompare) checkTipSetOutcome(r1, r2 interface{}) error {
+	prev, next := ac.dp.getParent().Key(), ac.dp.currentTS.Key()
+	outcome := func(key *types.TipSetKey) string {
+		switch {
+		case key == nil:
+			return outcomeError
+		case key.Equals(prev):
+			return outcomePrevious
+		case key.Equals(next):
+			return outcomeNext
+		}
+		return fmt.Sprintf("tipset %v", key)
+	}
+
+	var vKey, lKey *types.TipSetKey
+	if o1, _ := r1.(*types.TipSet); o1 != nil {
+		key := o1.Key()
+		vKey = &key
+	}
+	if o2, _ := r2.(*ltypes.TipSet); o2 != nil {
+		key := types.NewTipSetKey(o2.Cids()...)
+		lKey = &key
+	}
+
+	return checkOutcome(outcome(vKey), outcome(lKey))
+}
+
+// nullRoundBlockHashes returns the eth block hash of the previous and next tipset of the null rounds.
+func (ac *apiCompare) nullRoundBlockHashes() (types.EthHash, types.EthHash, error) {
+	prevCid, err := ac.dp.getParent().Key().Cid()
+	if err != nil {
+		return emptyEthHash, emptyEthHash, err
+	}
+	nextCid, err := ac.dp.currentTS.Key().Cid()
+	if err != nil {
+		return emptyEthHash, emptyEthHash, err
+	}
+	prevHash, err := types.EthHashFromCid(prevCid)
+	if err != nil {
+		return emptyEthHash, emptyEthHash, err
+	}
+	nextHash, err := types.EthHashFromCid(nextCid)
+
+	return prevHash, nextHash, err
+}
+
+func checkOutcome(vOutcome, lOutcome string) error {
+	if vOutcome != lOutcome {
+		return fmt.Errorf("venus returns %s, lotus returns %s", vOutcome, lOutcome)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/builtin"
+	ltypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+)
+
+const (
+	// the number of miners whose state objects are compared
+	objMinerSample = 5
+	// the name of block header object, which links the parent tipsets
+	blockHeaderObj = "block header"
+)
+
+// ipldObj is an object in blockstore, name is used to show which object it is.
+type ipldObj struct {
+	name string
+	c    cid.Cid
+}
+
+// chainObjs returns the headers, message AMTs and receipt AMTs of current tipset.
+func (ac *apiCompare) chainObjs() ([]ipldObj, error) {
+	var objs []ipldObj
+	for _, blk := range ac.dp.currentTS.Blocks() {
+		objs = append(objs,
+			ipldObj{name: blockHeaderObj, c: blk.Cid()},
+			ipldObj{name: "block messages", c: blk.Messages},
+			ipldObj{name: "parent receipts", c: blk.ParentMessageReceipts},
+		)
+
+		ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(chainReadObj))
+		data, err := ac.vAPI.ChainReadObj(ctx, blk.Messages)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read messages of block %s: %v", blk.Cid(), err)
+		}
+		var meta ltypes.MsgMeta
+		if err := meta.UnmarshalCBOR(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("failed to decode messages of block %s: %v", blk.Cid(), err)
+		}
+		objs = append(objs,
+			ipldObj{name: "bls messages", c: meta.BlsMessages},
+			ipldObj{name: "secpk messages", c: meta.SecpkMessages},
+		)
+	}
+
+	return objs, nil
+}
+
+// stateObjs returns a sample of state objects: the state root, the root of actors hamt and the heads of
+// builtin actors and some miners.
+func (ac *apiCompare) stateObjs() ([]ipldObj, error) {
+	ts := ac.dp.currentTS
+	objs := []ipldObj{{name: "state root", c: ts.ParentState()}}
+
+	ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(chainReadObj))
+	data, err := ac.vAPI.ChainReadObj(ctx, ts.ParentState())
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state root: %v", err)
+	}
+	var root ltypes.StateRoot
+	if err := root.UnmarshalCBOR(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to decode state root: %v", err)
+	}
+	objs = append(objs, ipldObj{name: "actors", c: root.Actors}, ipldObj{name: "state info", c: root.Info})
+
+	addrs := []address.Address{
+		builtin.SystemActorAddr,
+		builtin.InitActorAddr,
+		builtin.RewardActorAddr,
+		builtin.CronActorAddr,
+		builtin.StoragePowerActorAddr,
+		builtin.StorageMarketActorAddr,
+		builtin.VerifiedRegistryActorAddr,
+	}
+	miners := ac.dp.getMiners()
+	if len(miners) > objMinerSample {
+		miners = miners[:objMinerSample]
+	}
+	addrs = append(addrs, miners...)
+	for _, addr := range addrs {
+		ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(stateGetActor))
+		act, err := ac.vAPI.StateGetActor(ctx, addr, ts.Key())
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get actor %s: %v", addr, err)
+		}
+		objs = append(objs, ipldObj{name: fmt.Sprintf("head of %s", addr), c: act.Head})
+	}
+
+	return objs, nil
+}
+
+func (ac *apiCompare) CompareChainReadObj() error {
+	chainObjs, err := ac.chainObjs()
+	if err != nil {
+		return err
+	}
+	stateObjs, err := ac.stateObjs()
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range append(chainObjs, stateObjs...) {
+		err := ac.sendAndWait(chainReadObj, toInterface(ac.ctx, obj.c), withResultCheck(func(r1, r2 interface{}) error {
+			o1, _ := r1.([]byte)
+			o2, _ := r2.([]byte)
+			if !bytes.Equal(o1, o2) {
+				return fmt.Errorf("bytes not match, length %d != %d", len(o1), len(o2))
+			}
+			return nil
+		}))
+		if err != nil {
+			return fmt.Errorf("%s %s, error: %w", obj.name, obj.c, err)
+		}
+	}
+
+	return nil
+}
+
+func (ac *apiCompare) CompareChainHasObj() error {
+	chainObjs, err := ac.chainObjs()
+	if err != nil {
+		return err
+	}
+	stateObjs, err := ac.stateObjs()
+	if err != nil {
+		return err
+	}
+	// an object not exist in both nodes
+	missing, err := abi.CidBuilder.Sum([]byte(fmt.Sprintf("api-compare missing object %d", ac.dp.currentTS.Height())))
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range append(append(chainObjs, stateObjs...), ipldObj{name: "missing object", c: missing}) {
+		if err := ac.sendAndWait(chainHasObj, toInterface(ac.ctx, obj.c)); err != nil {
+			return fmt.Errorf("%s %s, error: %w", obj.name, obj.c, err)
+		}
+	}
+
+	return nil
+}
+
+// CompareChainStatObj only compares the message and receipt objects of chain, stat the state objects walks the
+// whole state tree and stat the block headers walks the whole chain, which are too expensive.
+func (ac *apiCompare) CompareChainStatObj() error {
+	objs, err := ac.chainObjs()
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objs {
+		if obj.name == blockHeaderObj {
+			continue
+		}
+		if err := ac.sendAndWait(chainStatObj, toInterface(ac.ctx, obj.c, cid.Undef)); err != nil {
+			return fmt.Errorf("%s %s, error: %w", obj.name, obj.c, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/ipfs/go-cid"
+)
+
+// replayAll replays every message in current tipset, and compares the events of the replayed receipts.
+func (ac *apiCompare) replayAll() error {
+	key := ac.dp.currentTS.Key()
+	for _, msg := range ac.dp.getTipSetMsgs() {
+		c := msg.Cid
+		var eventsRoot *cid.Cid
+		err := ac.sendAndWait(stateReplay, toInterface(ac.ctx, key, c), withResultCheck(func(r1, r2 interface{}) error {
+			if res, _ := r1.(*types.InvocResult); res != nil && res.MsgRct != nil {
+				eventsRoot = res.MsgRct.EventsRoot
+			}
+			return resultCheckWithInvocResult(c, r1, r2)
+		}))
+		if err != nil {
+			return err
+		}
+		if eventsRoot == nil {
+			continue
+		}
+
+		if err := ac.sendAndWait(chainGetEvents, toInterface(ac.ctx, *eventsRoot), withResultCheck(checkEventsResult)); err != nil {
+			return fmt.Errorf("msg %s, events root %s, error: %w", c, eventsRoot, err)
+		}
+	}
+
+	return nil
+}
+
+// callAll calls every message in current tipset on the parent state of current tipset.
+func (ac *apiCompare) callAll() error {
+	key := ac.dp.currentTS.Key()
+	for _, msg := range ac.dp.getTipSetMsgs() {
+		m := msg.Message
+		err := ac.sendAndWait(stateCall, toInterface(ac.ctx, m, key), withResultCheck(func(r1, r2 interface{}) error {
+			return resultCheckWithInvocResult(m.Cid(), r1, r2)
+		}))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CompareStateCompute compares the state root computed by lotus StateCompute with the parent state of the child
+// tipset on venus, as venus does not provide StateCompute, then compares the trace of every message with venus
+// StateReplay. Only run in exhaustive replay mode.
+func (ac *apiCompare) CompareStateCompute() error {
+	if !ac.cfg.exhaustiveReplay {
+		return nil
+	}
+
+	ts := ac.dp.currentTS
+	out, vRoot, err := ac.computeState(ts)
+	if err != nil {
+		return err
+	}
+	if vRoot != out.Root {
+		return markCritical(fmt.Errorf("state root not match %s != %s", vRoot, out.Root))
+	}
+
+	msgs := make(map[cid.Cid]struct{}, len(ac.dp.getTipSetMsgs()))
+	for _, msg := range ac.dp.getTipSetMsgs() {
+		msgs[msg.Cid] = struct{}{}
+	}
+	for _, trace := range out.Trace {
+		// implicit messages, eg: cron, can not be replayed
+		if _, ok := msgs[trace.MsgCid]; !ok {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(stateReplay))
+		vRes, err := ac.vAPI.StateReplay(ctx, ts.Key(), trace.MsgCid)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("venus replay %s failed: %w", trace.MsgCid, err)
+		}
+		if err := checkInvocResult(vRes, trace); err != nil {
+			return fmt.Errorf("msg %s, %w", trace.MsgCid, err)
+		}
+	}
+
+	return nil
+}
+
+// computeState computes the state of ts with lotus StateCompute, and returns it with the parent state of
+// the child tipset on venus.
+func (ac *apiCompare) computeState(ts *types.TipSet) (*lapi.ComputeStateOutput, cid.Cid, error) {
+	ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(stateCompute))
+	defer cancel()
+
+	out, err := ac.lAPI.StateCompute(ctx, ts.Height(), nil, toLoutsTipsetKey(ts.Key()))
+	if err != nil {
+		return nil, cid.Undef, fmt.Errorf("lotus compute state failed: %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(ac.ctx, ac.handler.timeouts.get(chainGetTipSetAfterHeight))
+	defer cancel()
+
+	child, err := ac.vAPI.ChainGetTipSetAfterHeight(ctx, ts.Height()+1, types.EmptyTSK)
+	if err != nil {
+		return nil, cid.Undef, fmt.Errorf("failed to get child tipset: %v", err)
+	}
+	if !child.Parents().Equals(ts.Key()) {
+		return nil, cid.Undef, fmt.Errorf("child tipset %d not found, parents %v", ts.Height()+1, child.Parents())
+	}
+
+	return out, child.ParentState(), nil
+}
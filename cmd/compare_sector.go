@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+)
+
+// forEachSector calls f for each sector collected from PreCommit and ProveCommit messages, sector may be
+// not precommitted or already proven at current tipset, so both nodes return error is expected.
+func (ac *apiCompare) forEachSector(method string) error {
+	key := ac.dp.currentTS.Key()
+	for _, sector := range ac.dp.getSectors() {
+		err := ac.sendAndWait(method, toInterface(ac.ctx, sector.miner, sector.number, key), withExpectCallAPIError())
+		if err != nil {
+			return fmt.Errorf("miner %s, sector %d, error: %w", sector.miner, sector.number, err)
+		}
+	}
+
+	return nil
+}
+
+func (ac *apiCompare) CompareStateSectorGetInfo() error {
+	return ac.forEachSector(stateSectorGetInfo)
+}
+
+func (ac *apiCompare) CompareStateSectorPreCommitInfo() error {
+	return ac.forEachSector(stateSectorPreCommitInfo)
+}
+
+func (ac *apiCompare) CompareStateSectorExpiration() error {
+	return ac.forEachSector(stateSectorExpiration)
+}
+
+func (ac *apiCompare) CompareStateSectorPartition() error {
+	return ac.forEachSector(stateSectorPartition)
+}
+
+func (ac *apiCompare) CompareStateMinerSectorAllocated() error {
+	return ac.forEachSector(stateMinerSectorAllocated)
+}
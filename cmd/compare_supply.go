@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/builtin"
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/venus/venus-shared/types"
+)
+
+// The supply and economics data must be exactly the same, a mismatch means consensus divergence,
+// so all of them are marked as critical.
+
+func (ac *apiCompare) CompareStateCirculatingSupply() error {
+	return ac.sendAndWait(stateCirculatingSupply, toInterface(ac.ctx, ac.dp.currentTS.Key()), withCritical(), withResultCheck(func(r1, r2 interface{}) error {
+		o1, _ := r1.(big.Int)
+		o2, _ := r2.(big.Int)
+		return bigIntEqual(&o1, &o2)
+	}))
+}
+
+func (ac *apiCompare) CompareStateVMCirculatingSupplyInternal() error {
+	return ac.sendAndWait(stateVMCirculatingSupplyInternal, toInterface(ac.ctx, ac.dp.currentTS.Key()), withCritical())
+}
+
+func (ac *apiCompare) CompareChainTipSetWeight() error {
+	return ac.sendAndWait(chainTipSetWeight, toInterface(ac.ctx, ac.dp.currentTS.Key()), withCritical(), withResultCheck(func(r1, r2 interface{}) error {
+		o1, _ := r1.(big.Int)
+		o2, _ := r2.(big.Int)
+		return bigIntEqual(&o1, &o2)
+	}))
+}
+
+func (ac *apiCompare) CompareStateReadStateRewardAndPower() error {
+	key := ac.dp.currentTS.Key()
+	for _, addr := range []address.Address{builtin.RewardActorAddr, builtin.StoragePowerActorAddr} {
+		if err := ac.sendAndWait(stateReadState, toInterface(ac.ctx, addr, key), withCritical()); err != nil {
+			return fmt.Errorf("actor %s, error: %w", addr, err)
+		}
+	}
+
+	return nil
+}
+
+// checkParticipantsTotal compares the total escrow and locked funds of market participants.
+func checkParticipantsTotal(vBalances map[string]types.MarketBalance, lBalances map[string]api.MarketBalance) error {
+	escrow, locked := big.Zero(), big.Zero()
+	for _, b := range vBalances {
+		escrow = big.Add(escrow, b.Escrow)
+		locked = big.Add(locked, b.Locked)
+	}
+	escrow2, locked2 := big.Zero(), big.Zero()
+	for _, b := range lBalances {
+		escrow2 = big.Add(escrow2, b.Escrow)
+		locked2 = big.Add(locked2, b.Locked)
+	}
+
+	if err := bigIntEqual(&escrow, &escrow2); err != nil {
+		return fmt.Errorf("total escrow %w", err)
+	}
+	if err := bigIntEqual(&locked, &locked2); err != nil {
+		return fmt.Errorf("total locked %w", err)
+	}
+
+	return nil
+}
@@ -12,6 +12,7 @@ const (
 	stateAccountKey              = "StateAccountKey"
 	chainGetTipSet               = "ChainGetTipSet"
 	chainGetTipSetByHeight       = "ChainGetTipSetByHeight"
+	chainGetTipSetAfterHeight    = "ChainGetTipSetAfterHeight"
 	stateGetRandomnessFromBeacon = "StateGetRandomnessFromBeacon"
 	stateGetBeaconEntry          = "StateGetBeaconEntry"
 	chainGetBlock                = "ChainGetBlock"
@@ -33,7 +34,74 @@ const (
 	stateActorManifestCID        = "StateActorManifestCID"
 	stateCall                    = "StateCall"
 	stateReplay                  = "StateReplay"
+	stateCompute                 = "StateCompute"
 	minerGetBaseInfo             = "MinerGetBaseInfo"
+	chainHead                    = "ChainHead"
+
+	// miner
+	stateMinerInfo                    = "StateMinerInfo"
+	stateMinerPower                   = "StateMinerPower"
+	stateMinerSectors                 = "StateMinerSectors"
+	stateMinerActiveSectors           = "StateMinerActiveSectors"
+	stateMinerFaults                  = "StateMinerFaults"
+	stateMinerRecoveries              = "StateMinerRecoveries"
+	stateMinerDeadlines               = "StateMinerDeadlines"
+	stateMinerPartitions              = "StateMinerPartitions"
+	stateMinerProvingDeadline         = "StateMinerProvingDeadline"
+	stateMinerAvailableBalance        = "StateMinerAvailableBalance"
+	stateMinerSectorCount             = "StateMinerSectorCount"
+	stateMinerInitialPledgeCollateral = "StateMinerInitialPledgeCollateral"
+
+	// actor
+	stateGetActor            = "StateGetActor"
+	stateLookupID            = "StateLookupID"
+	stateLookupRobustAddress = "StateLookupRobustAddress"
+	stateListActors          = "StateListActors"
+	stateListMiners          = "StateListMiners"
+
+	// events
+	chainGetEvents = "ChainGetEvents"
+
+	// blockstore
+	chainReadObj = "ChainReadObj"
+	chainHasObj  = "ChainHasObj"
+	chainStatObj = "ChainStatObj"
+
+	// gas
+	gasEstimateMessageGas = "GasEstimateMessageGas"
+	gasEstimateGasLimit   = "GasEstimateGasLimit"
+	gasEstimateFeeCap     = "GasEstimateFeeCap"
+	gasEstimateGasPremium = "GasEstimateGasPremium"
+
+	// mpool
+	mpoolPending       = "MpoolPending"
+	mpoolGetNonce      = "MpoolGetNonce"
+	mpoolSelect        = "MpoolSelect"
+	mpoolGetConfig     = "MpoolGetConfig"
+	mpoolCheckMessages = "MpoolCheckMessages"
+
+	// supply
+	stateCirculatingSupply           = "StateCirculatingSupply"
+	stateVMCirculatingSupplyInternal = "StateVMCirculatingSupplyInternal"
+	chainTipSetWeight                = "ChainTipSetWeight"
+
+	// sector
+	stateSectorGetInfo        = "StateSectorGetInfo"
+	stateSectorPreCommitInfo  = "StateSectorPreCommitInfo"
+	stateSectorExpiration     = "StateSectorExpiration"
+	stateSectorPartition      = "StateSectorPartition"
+	stateMinerSectorAllocated = "StateMinerSectorAllocated"
+
+	// market
+	stateMarketBalance                = "StateMarketBalance"
+	stateMarketDeals                  = "StateMarketDeals"
+	stateMarketStorageDeal            = "StateMarketStorageDeal"
+	stateMarketParticipants           = "StateMarketParticipants"
+	stateDealProviderCollateralBounds = "StateDealProviderCollateralBounds"
+	stateGetAllocation                = "StateGetAllocation"
+	stateGetClaim                     = "StateGetClaim"
+	stateGetAllocations               = "StateGetAllocations"
+	stateGetClaims                    = "StateGetClaims"
 
 	// state
 	stateReadState    = "StateReadState"
@@ -68,12 +136,25 @@ const (
 	web3ClientVersion                      = "Web3ClientVersion"
 	ethGetTransactionHashByCid             = "EthGetTransactionHashByCid"
 	ethGetMessageCidByTransactionHash      = "EthGetMessageCidByTransactionHash"
+	ethTraceBlock                          = "EthTraceBlock"
+	ethTraceReplayBlockTransactions        = "EthTraceReplayBlockTransactions"
+
+	// eth logs and filters
+	ethGetLogs                     = "EthGetLogs"
+	ethNewFilter                   = "EthNewFilter"
+	ethNewBlockFilter              = "EthNewBlockFilter"
+	ethNewPendingTransactionFilter = "EthNewPendingTransactionFilter"
+	ethGetFilterChanges            = "EthGetFilterChanges"
+	ethGetFilterLogs               = "EthGetFilterLogs"
+	ethUninstallFilter             = "EthUninstallFilter"
 )
 
 const (
-	blkParamsEarliest = "earliest"
-	blkParamsPending  = "pending"
-	blkParamsLatest   = "latest"
+	blkParamsEarliest  = "earliest"
+	blkParamsPending   = "pending"
+	blkParamsLatest    = "latest"
+	blkParamsSafe      = "safe"
+	blkParamsFinalized = "finalized"
 )
 
 var (
@@ -1,24 +1,41 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"strings"
 
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/builtin"
+	"github.com/filecoin-project/go-state-types/builtin/v10/eam"
+	"github.com/filecoin-project/go-state-types/builtin/v9/market"
+	"github.com/filecoin-project/go-state-types/builtin/v9/miner"
 	"github.com/filecoin-project/lotus/chain/types/ethtypes"
+	vbuiltin "github.com/filecoin-project/venus/venus-shared/actors/builtin"
 	v1 "github.com/filecoin-project/venus/venus-shared/api/chain/v1"
 	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/ipfs/go-cid"
+	"github.com/sirupsen/logrus"
 )
 
-func newDataProvider(ctx context.Context, api v1.FullNode) (*dataProvider, error) {
+const (
+	maxRecentDeals   = 50
+	maxRecentAddrs   = 20
+	maxRecentSectors = 50
+	maxRecentEthData = 10
+)
+
+func newDataProvider(ctx context.Context, api v1.FullNode, timeouts *callTimeouts) (*dataProvider, error) {
 	defaultMiner, err := address.NewFromString("t01000")
 	if err != nil {
 		return nil, err
 	}
 	return &dataProvider{
-		ctx: ctx,
-		api: api,
+		ctx:      ctx,
+		api:      api,
+		timeouts: timeouts,
 		dataSet: &dataSet{
 			defaultMiner: defaultMiner,
 		},
@@ -26,8 +43,9 @@ func newDataProvider(ctx context.Context, api v1.FullNode) (*dataProvider, error
 }
 
 type dataProvider struct {
-	ctx context.Context
-	api v1.FullNode
+	ctx      context.Context
+	api      v1.FullNode
+	timeouts *callTimeouts
 
 	currentTS *types.TipSet
 
@@ -40,6 +58,45 @@ type dataSet struct {
 	blockMsgs []*types.Message
 	senders   []address.Address
 	ids       []address.Address
+	miners    []address.Address
+	parent    *types.TipSet
+	// heights of null rounds between the parent and current tipset
+	nullRounds []abi.ChainEpoch
+	// messages included in current tipset
+	tipsetMsgs []types.MessageCID
+	// events roots of receipts in current tipset
+	eventsRoots []cid.Cid
+
+	// collected from PublishStorageDeals messages, keep the recent ones across heights
+	dealIDs       []abi.DealID
+	dealClients   []address.Address
+	dealProviders []address.Address
+	// collected from PreCommit and ProveCommit messages, keep the recent ones across heights
+	sectors []minerSector
+	// whether the address is a miner actor, the type of an actor never changes
+	minerActors map[address.Address]bool
+	// senders and receivers of messages grouped by protocol, keep the recent ones across heights
+	addrs map[address.Protocol][]address.Address
+	// collected from EAM create messages, keep the recent ones across heights
+	contracts []ethContract
+	// collected from InvokeContract messages, keep the recent ones across heights
+	ethCalls []ethCallSample
+}
+
+type ethContract struct {
+	addr     types.EthAddress
+	initcode []byte
+}
+
+type ethCallSample struct {
+	from address.Address
+	to   address.Address
+	data []byte
+}
+
+type minerSector struct {
+	miner  address.Address
+	number abi.SectorNumber
 }
 
 func (dp *dataProvider) reset(ts *types.TipSet) error {
@@ -52,12 +109,43 @@ func (dp *dataProvider) reset(ts *types.TipSet) error {
 }
 
 func (dp *dataProvider) generateData() error {
+	dp.dataSet.miners = dp.dataSet.miners[:0]
+	for _, blk := range dp.currentTS.Blocks() {
+		if !containAddress(dp.dataSet.miners, blk.Miner) {
+			dp.dataSet.miners = append(dp.dataSet.miners, blk.Miner)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(dp.ctx, dp.timeouts.get(chainGetTipSet))
+	parent, err := dp.api.ChainGetTipSet(ctx, dp.currentTS.Parents())
+	cancel()
+	if err != nil {
+		return err
+	}
+	dp.dataSet.parent = parent
+	dp.dataSet.nullRounds = dp.dataSet.nullRounds[:0]
+	for h := parent.Height() + 1; h < dp.currentTS.Height(); h++ {
+		dp.dataSet.nullRounds = append(dp.dataSet.nullRounds, h)
+	}
+
+	ctx, cancel = context.WithTimeout(dp.ctx, dp.timeouts.get(chainGetMessagesInTipset))
+	tipsetMsgs, err := dp.api.ChainGetMessagesInTipset(ctx, dp.currentTS.Key())
+	cancel()
+	if err != nil {
+		return err
+	}
+	dp.dataSet.tipsetMsgs = tipsetMsgs
+
 	blk := dp.currentTS.Blocks()[0].Cid()
-	blkMsgs, err := dp.api.ChainGetParentMessages(dp.ctx, blk)
+	ctx, cancel = context.WithTimeout(dp.ctx, dp.timeouts.get(chainGetParentMessages))
+	blkMsgs, err := dp.api.ChainGetParentMessages(ctx, blk)
+	cancel()
 	if err != nil {
 		return err
 	}
-	receipts, err := dp.api.ChainGetParentReceipts(dp.ctx, blk)
+	ctx, cancel = context.WithTimeout(dp.ctx, dp.timeouts.get(chainGetParentReceipts))
+	receipts, err := dp.api.ChainGetParentReceipts(ctx, blk)
+	cancel()
 	if err != nil {
 		return err
 	}
@@ -69,6 +157,7 @@ func (dp *dataProvider) generateData() error {
 	senders := make(map[address.Address]struct{}, msgLen)
 	msgs := make([]*types.Message, 0, msgLen)
 	msgWithEventRoot := make([]*types.Message, 0)
+	dp.dataSet.eventsRoots = dp.dataSet.eventsRoots[:0]
 	for i, msg := range blkMsgs {
 		receipt := receipts[i]
 		if receipt.ExitCode.IsError() {
@@ -78,7 +167,13 @@ func (dp *dataProvider) generateData() error {
 			ids[msg.Message.To] = struct{}{}
 		}
 		senders[msg.Message.From] = struct{}{}
+		dp.collectAddress(msg.Message.From)
+		dp.collectAddress(msg.Message.To)
+		dp.collectDeals(msg.Message, receipt)
+		dp.collectSectors(msg.Message)
+		dp.collectContracts(msg.Message, receipt)
 		if receipt.EventsRoot != nil {
+			dp.dataSet.eventsRoots = append(dp.dataSet.eventsRoots, *receipt.EventsRoot)
 			msgWithEventRoot = append(msgWithEventRoot, msg.Message)
 			continue
 		}
@@ -86,13 +181,13 @@ func (dp *dataProvider) generateData() error {
 	}
 
 	if len(ids) != 0 {
-		dp.dataSet.ids = make([]address.Address, len(ids))
+		dp.dataSet.ids = make([]address.Address, 0, len(ids))
 		for addr := range ids {
 			dp.dataSet.ids = append(dp.dataSet.ids, addr)
 		}
 	}
 	if len(senders) != 0 {
-		dp.dataSet.senders = make([]address.Address, len(senders))
+		dp.dataSet.senders = make([]address.Address, 0, len(senders))
 		for addr := range senders {
 			dp.dataSet.senders = append(dp.dataSet.senders, addr)
 		}
@@ -104,6 +199,243 @@ func (dp *dataProvider) generateData() error {
 	return nil
 }
 
+func (dp *dataProvider) collectAddress(addr address.Address) {
+	if dp.dataSet.addrs == nil {
+		dp.dataSet.addrs = make(map[address.Protocol][]address.Address)
+	}
+	dp.dataSet.addrs[addr.Protocol()] = appendRecent(dp.dataSet.addrs[addr.Protocol()], maxRecentAddrs, addr)
+}
+
+// getAddresses returns the addresses of all protocols, ordered by protocol.
+func (dp *dataProvider) getAddresses() []address.Address {
+	var out []address.Address
+	for _, p := range []address.Protocol{address.ID, address.SECP256K1, address.Actor, address.BLS, address.Delegated} {
+		out = append(out, dp.dataSet.addrs[p]...)
+	}
+
+	return out
+}
+
+// collectDeals collects deal ids, clients and providers from PublishStorageDeals message.
+func (dp *dataProvider) collectDeals(msg *types.Message, receipt *types.MessageReceipt) {
+	if msg.To != builtin.StorageMarketActorAddr || msg.Method != builtin.MethodsMarket.PublishStorageDeals {
+		return
+	}
+
+	var params market.PublishStorageDealsParams
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		logrus.Debugf("decode PublishStorageDeals params of %s failed: %v", msg.Cid(), err)
+		return
+	}
+	for _, deal := range params.Deals {
+		dp.dataSet.dealClients = appendRecent(dp.dataSet.dealClients, maxRecentAddrs, deal.Proposal.Client)
+		dp.dataSet.dealProviders = appendRecent(dp.dataSet.dealProviders, maxRecentAddrs, deal.Proposal.Provider)
+	}
+
+	var ret market.PublishStorageDealsReturn
+	if err := ret.UnmarshalCBOR(bytes.NewReader(receipt.Return)); err != nil {
+		logrus.Debugf("decode PublishStorageDeals return of %s failed: %v", msg.Cid(), err)
+		return
+	}
+	for _, id := range ret.IDs {
+		dp.dataSet.dealIDs = appendRecent(dp.dataSet.dealIDs, maxRecentDeals, id)
+	}
+}
+
+// collectSectors collects sector numbers from PreCommit and ProveCommit messages.
+func (dp *dataProvider) collectSectors(msg *types.Message) {
+	var numbers []abi.SectorNumber
+	r := bytes.NewReader(msg.Params)
+
+	switch msg.Method {
+	case builtin.MethodsMiner.PreCommitSector:
+		var params miner.PreCommitSectorParams
+		if err := params.UnmarshalCBOR(r); err != nil {
+			return
+		}
+		numbers = append(numbers, params.SectorNumber)
+	case builtin.MethodsMiner.PreCommitSectorBatch:
+		var params miner.PreCommitSectorBatchParams
+		if err := params.UnmarshalCBOR(r); err != nil {
+			return
+		}
+		for _, sector := range params.Sectors {
+			numbers = append(numbers, sector.SectorNumber)
+		}
+	case builtin.MethodsMiner.PreCommitSectorBatch2:
+		var params miner.PreCommitSectorBatchParams2
+		if err := params.UnmarshalCBOR(r); err != nil {
+			return
+		}
+		for _, sector := range params.Sectors {
+			numbers = append(numbers, sector.SectorNumber)
+		}
+	case builtin.MethodsMiner.ProveCommitSector:
+		var params miner.ProveCommitSectorParams
+		if err := params.UnmarshalCBOR(r); err != nil {
+			return
+		}
+		numbers = append(numbers, params.SectorNumber)
+	case builtin.MethodsMiner.ProveCommitAggregate:
+		var params miner.ProveCommitAggregateParams
+		if err := params.UnmarshalCBOR(r); err != nil {
+			return
+		}
+		if err := params.SectorNumbers.ForEach(func(n uint64) error {
+			numbers = append(numbers, abi.SectorNumber(n))
+			return nil
+		}); err != nil {
+			return
+		}
+	default:
+		return
+	}
+
+	// other actors may have the same method numbers
+	if !dp.isMinerActor(msg.To) {
+		return
+	}
+	for _, n := range numbers {
+		sector := minerSector{miner: msg.To, number: n}
+		if !containSector(dp.dataSet.sectors, sector) {
+			dp.dataSet.sectors = append(dp.dataSet.sectors, sector)
+		}
+	}
+	if len(dp.dataSet.sectors) > maxRecentSectors {
+		dp.dataSet.sectors = dp.dataSet.sectors[len(dp.dataSet.sectors)-maxRecentSectors:]
+	}
+}
+
+func (dp *dataProvider) isMinerActor(addr address.Address) bool {
+	if isMiner, ok := dp.dataSet.minerActors[addr]; ok {
+		return isMiner
+	}
+	ctx, cancel := context.WithTimeout(dp.ctx, dp.timeouts.get(stateGetActor))
+	defer cancel()
+
+	actor, err := dp.api.StateGetActor(ctx, addr, dp.currentTS.Key())
+	if err != nil {
+		logrus.Debugf("get actor %s failed: %v", addr, err)
+		return false
+	}
+	if dp.dataSet.minerActors == nil {
+		dp.dataSet.minerActors = make(map[address.Address]bool)
+	}
+	dp.dataSet.minerActors[addr] = vbuiltin.IsStorageMinerActor(actor.Code)
+
+	return dp.dataSet.minerActors[addr]
+}
+
+// collectContracts collects contracts created by EAM, and the calldata of InvokeContract messages.
+func (dp *dataProvider) collectContracts(msg *types.Message, receipt *types.MessageReceipt) {
+	if msg.To != builtin.EthereumAddressManagerActorAddr {
+		if msg.Method != builtin.MethodsEVM.InvokeContract {
+			return
+		}
+		var data abi.CborBytes
+		if len(msg.Params) > 0 {
+			if err := data.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+				logrus.Debugf("decode InvokeContract params of %s failed: %v", msg.Cid(), err)
+				return
+			}
+		}
+		dp.dataSet.ethCalls = append(dp.dataSet.ethCalls, ethCallSample{from: msg.From, to: msg.To, data: data})
+		if len(dp.dataSet.ethCalls) > maxRecentEthData {
+			dp.dataSet.ethCalls = dp.dataSet.ethCalls[len(dp.dataSet.ethCalls)-maxRecentEthData:]
+		}
+		return
+	}
+
+	var initcode []byte
+	r := bytes.NewReader(msg.Params)
+	switch msg.Method {
+	case builtin.MethodsEAM.Create:
+		var params eam.CreateParams
+		if err := params.UnmarshalCBOR(r); err != nil {
+			return
+		}
+		initcode = params.Initcode
+	case builtin.MethodsEAM.Create2:
+		var params eam.Create2Params
+		if err := params.UnmarshalCBOR(r); err != nil {
+			return
+		}
+		initcode = params.Initcode
+	case builtin.MethodsEAM.CreateExternal:
+		var params abi.CborBytes
+		if err := params.UnmarshalCBOR(r); err != nil {
+			return
+		}
+		initcode = params
+	default:
+		return
+	}
+
+	// the return of Create, Create2 and CreateExternal have the same encoding
+	var ret eam.CreateReturn
+	if err := ret.UnmarshalCBOR(bytes.NewReader(receipt.Return)); err != nil {
+		logrus.Debugf("decode EAM return of %s failed: %v", msg.Cid(), err)
+		return
+	}
+	dp.dataSet.contracts = append(dp.dataSet.contracts, ethContract{addr: ret.EthAddress, initcode: initcode})
+	if len(dp.dataSet.contracts) > maxRecentEthData {
+		dp.dataSet.contracts = dp.dataSet.contracts[len(dp.dataSet.contracts)-maxRecentEthData:]
+	}
+}
+
+func (dp *dataProvider) getContracts() []ethContract {
+	return dp.dataSet.contracts
+}
+
+func (dp *dataProvider) getEthCalls() []ethCallSample {
+	return dp.dataSet.ethCalls
+}
+
+func containSector(list []minerSector, sector minerSector) bool {
+	for _, s := range list {
+		if s == sector {
+			return true
+		}
+	}
+	return false
+}
+
+func (dp *dataProvider) getSectors() []minerSector {
+	return dp.dataSet.sectors
+}
+
+func (dp *dataProvider) getDealIDs() []abi.DealID {
+	return dp.dataSet.dealIDs
+}
+
+func (dp *dataProvider) getDealClients() []address.Address {
+	return dp.dataSet.dealClients
+}
+
+// getDealProviders returns the providers of recent deals, or the miners if no deal found.
+func (dp *dataProvider) getDealProviders() []address.Address {
+	if len(dp.dataSet.dealProviders) == 0 {
+		return dp.getMiners()
+	}
+	return dp.dataSet.dealProviders
+}
+
+func (dp *dataProvider) getParent() *types.TipSet {
+	return dp.dataSet.parent
+}
+
+func (dp *dataProvider) getNullRounds() []abi.ChainEpoch {
+	return dp.dataSet.nullRounds
+}
+
+func (dp *dataProvider) getTipSetMsgs() []types.MessageCID {
+	return dp.dataSet.tipsetMsgs
+}
+
+func (dp *dataProvider) getEventsRoots() []cid.Cid {
+	return dp.dataSet.eventsRoots
+}
+
 func (dp *dataProvider) getMsgs() []*types.Message {
 	return dp.dataSet.blockMsgs
 }
@@ -115,7 +447,6 @@ func (dp *dataProvider) getMsg() *types.Message {
 	return nil
 }
 
-// nolint
 func (dp *dataProvider) getSenders() []address.Address {
 	return dp.dataSet.senders
 }
@@ -137,6 +468,14 @@ func (dp *dataProvider) getIDAddress() address.Address {
 	return dp.defaultMiner()
 }
 
+// getMiners returns the miners which mined blocks in current tipset.
+func (dp *dataProvider) getMiners() []address.Address {
+	if len(dp.dataSet.miners) == 0 {
+		return []address.Address{dp.defaultMiner()}
+	}
+	return dp.dataSet.miners
+}
+
 func (dp *dataProvider) defaultMiner() address.Address {
 	return dp.dataSet.defaultMiner
 }
@@ -174,28 +513,6 @@ func (dp *dataProvider) getBlockHash() (types.EthHash, ethtypes.EthHash, error)
 	return blkHash, blkHash2, nil
 }
 
-func (dp *dataProvider) getTxHash() (types.EthHash, ethtypes.EthHash, error) {
-	msg := dp.getMsg()
-
-	if msg != nil {
-		msgHash, err := types.EthHashFromCid(msg.Cid())
-		if err != nil {
-			return emptyEthHash, emptyLEthHash, err
-		}
-		msgHash2, err := ethtypes.EthHashFromCid(msg.Cid())
-		if err != nil {
-			return emptyEthHash, emptyLEthHash, err
-		}
-		if msgHash != types.EthHash(msgHash2) {
-			return emptyEthHash, emptyLEthHash, fmt.Errorf("msg hash not match %v %v %v", msgHash, msgHash2, msg.Cid())
-		}
-
-		return msgHash, msgHash2, err
-	}
-
-	return emptyEthHash, emptyLEthHash, nil
-}
-
 func (dp *dataProvider) getEthAddress() (types.EthAddress, ethtypes.EthAddress, error) {
 	addr, err := types.EthAddressFromFilecoinAddress(dp.defaultMiner())
 	if err != nil {
@@ -1,10 +1,20 @@
 package cmd
 
 import (
+	"bytes"
 	"testing"
 
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/builtin"
+	"github.com/filecoin-project/go-state-types/builtin/v10/eam"
+	"github.com/filecoin-project/go-state-types/builtin/v9/market"
+	"github.com/filecoin-project/go-state-types/builtin/v9/miner"
+	"github.com/filecoin-project/go-state-types/crypto"
 	"github.com/filecoin-project/venus/venus-shared/testutil"
 	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/ipfs/go-cid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -19,3 +29,130 @@ func TestGetBlockHash(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, blkHash.ToCid(), blkHash2.ToCid())
 }
+
+func TestCollectDeals(t *testing.T) {
+	client, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+	provider, err := address.NewIDAddress(1002)
+	require.NoError(t, err)
+
+	var pieceCID cid.Cid
+	testutil.Provide(t, &pieceCID)
+	params := market.PublishStorageDealsParams{
+		Deals: []market.ClientDealProposal{
+			{Proposal: market.DealProposal{
+				PieceCID: pieceCID,
+				Client:   client,
+				Provider: provider,
+				Label:    market.EmptyDealLabel,
+			}, ClientSignature: crypto.Signature{Type: crypto.SigTypeSecp256k1}},
+		},
+	}
+	buf := new(bytes.Buffer)
+	require.NoError(t, params.MarshalCBOR(buf))
+	msg := &types.Message{
+		To:     builtin.StorageMarketActorAddr,
+		From:   client,
+		Method: builtin.MethodsMarket.PublishStorageDeals,
+		Params: buf.Bytes(),
+	}
+
+	ret := market.PublishStorageDealsReturn{
+		IDs:        []abi.DealID{10},
+		ValidDeals: bitfield.NewFromSet([]uint64{0}),
+	}
+	buf = new(bytes.Buffer)
+	require.NoError(t, ret.MarshalCBOR(buf))
+
+	dp := &dataProvider{dataSet: &dataSet{}}
+	dp.collectDeals(msg, &types.MessageReceipt{Return: buf.Bytes()})
+	// duplicate
+	dp.collectDeals(msg, &types.MessageReceipt{Return: buf.Bytes()})
+
+	require.Equal(t, []abi.DealID{10}, dp.getDealIDs())
+	require.Equal(t, []address.Address{client}, dp.getDealClients())
+	require.Equal(t, []address.Address{provider}, dp.getDealProviders())
+}
+
+func TestCollectSectors(t *testing.T) {
+	maddr, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+
+	params := miner.ProveCommitAggregateParams{
+		SectorNumbers: bitfield.NewFromSet([]uint64{1, 3}),
+	}
+	buf := new(bytes.Buffer)
+	require.NoError(t, params.MarshalCBOR(buf))
+
+	other, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+
+	dp := &dataProvider{dataSet: &dataSet{minerActors: map[address.Address]bool{maddr: true, other: false}}}
+	msg := &types.Message{
+		To:     maddr,
+		Method: builtin.MethodsMiner.ProveCommitAggregate,
+		Params: buf.Bytes(),
+	}
+	dp.collectSectors(msg)
+	// duplicate
+	dp.collectSectors(msg)
+	// not a miner actor
+	dp.collectSectors(&types.Message{To: other, Method: msg.Method, Params: msg.Params})
+
+	require.Equal(t, []minerSector{{miner: maddr, number: 1}, {miner: maddr, number: 3}}, dp.getSectors())
+}
+
+func TestCollectAddress(t *testing.T) {
+	id, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	f4, err := address.NewDelegatedAddress(10, []byte("test"))
+	require.NoError(t, err)
+
+	dp := &dataProvider{dataSet: &dataSet{}}
+	dp.collectAddress(f4)
+	dp.collectAddress(id)
+	dp.collectAddress(id)
+
+	require.Equal(t, []address.Address{id, f4}, dp.getAddresses())
+}
+
+func TestCollectContracts(t *testing.T) {
+	sender, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	contract, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+
+	params := eam.CreateParams{Initcode: []byte{1, 2, 3}}
+	buf := new(bytes.Buffer)
+	require.NoError(t, params.MarshalCBOR(buf))
+	msg := &types.Message{
+		To:     builtin.EthereumAddressManagerActorAddr,
+		From:   sender,
+		Method: builtin.MethodsEAM.Create,
+		Params: buf.Bytes(),
+	}
+	ret := eam.CreateReturn{ActorID: 1001, EthAddress: [20]byte{1}}
+	retBuf := new(bytes.Buffer)
+	require.NoError(t, ret.MarshalCBOR(retBuf))
+
+	dp := &dataProvider{dataSet: &dataSet{}}
+	dp.collectContracts(msg, &types.MessageReceipt{Return: retBuf.Bytes()})
+	require.Equal(t, []ethContract{{addr: types.EthAddress{1}, initcode: []byte{1, 2, 3}}}, dp.getContracts())
+
+	data := abi.CborBytes{4, 5, 6}
+	buf.Reset()
+	require.NoError(t, data.MarshalCBOR(buf))
+	msg = &types.Message{
+		To:     contract,
+		From:   sender,
+		Method: builtin.MethodsEVM.InvokeContract,
+		Params: buf.Bytes(),
+	}
+	dp.collectContracts(msg, &types.MessageReceipt{})
+	require.Equal(t, []ethCallSample{{from: sender, to: contract, data: []byte{4, 5, 6}}}, dp.getEthCalls())
+
+	// other messages are ignored
+	msg = &types.Message{To: contract, From: sender, Method: builtin.MethodSend}
+	dp.collectContracts(msg, &types.MessageReceipt{})
+	require.Len(t, dp.getEthCalls(), 1)
+}
@@ -0,0 +1,373 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-jsonrpc"
+	"github.com/filecoin-project/go-state-types/builtin"
+	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types/ethtypes"
+	v1 "github.com/filecoin-project/venus/venus-shared/api/chain/v1"
+	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ethSubNewHeads = "newHeads"
+	ethSubLogs     = "logs"
+	// the number of taken subscriptions remembered to drop their late notifications
+	maxTakenSubs = 100
+)
+
+var ethSubKinds = []string{ethSubNewHeads, ethSubLogs}
+
+// ethSubRPCOptions registers h to receive eth_subscription notifications, only works with websocket.
+func ethSubRPCOptions(h *ethSubHandler) []jsonrpc.Option {
+	return []jsonrpc.Option{
+		jsonrpc.WithClientHandler("Filecoin", h),
+		jsonrpc.WithClientHandlerAlias("eth_subscription", "Filecoin.EthSubscription"),
+	}
+}
+
+func newEthSubHandler() *ethSubHandler {
+	return &ethSubHandler{
+		received: make(map[types.EthSubscriptionID][]json.RawMessage),
+		taken:    make(map[types.EthSubscriptionID]struct{}),
+	}
+}
+
+// ethSubHandler collects the notifications of one node by subscription id, notifications may arrive
+// before EthSubscribe returns, so they are kept until taken. The notifications in flight when unsubscribing
+// arrive after taken, they are dropped.
+type ethSubHandler struct {
+	lk       sync.Mutex
+	received map[types.EthSubscriptionID][]json.RawMessage
+	// the recent taken subscriptions, in the order of taken
+	taken      map[types.EthSubscriptionID]struct{}
+	takenOrder []types.EthSubscriptionID
+}
+
+type ethSubResponse struct {
+	SubscriptionID types.EthSubscriptionID `json:"subscription"`
+	Result         json.RawMessage         `json:"result"`
+}
+
+func (h *ethSubHandler) EthSubscription(ctx context.Context, r jsonrpc.RawParams) error {
+	resp, err := jsonrpc.DecodeParams[ethSubResponse](r)
+	if err != nil {
+		return err
+	}
+
+	h.lk.Lock()
+	defer h.lk.Unlock()
+	if _, ok := h.taken[resp.SubscriptionID]; ok {
+		return nil
+	}
+	h.received[resp.SubscriptionID] = append(h.received[resp.SubscriptionID], resp.Result)
+
+	return nil
+}
+
+// take returns and removes the notifications of id, the notifications of id arrive later are dropped.
+func (h *ethSubHandler) take(id types.EthSubscriptionID) []json.RawMessage {
+	h.lk.Lock()
+	defer h.lk.Unlock()
+
+	out := h.received[id]
+	delete(h.received, id)
+
+	if _, ok := h.taken[id]; !ok {
+		h.taken[id] = struct{}{}
+		h.takenOrder = append(h.takenOrder, id)
+		if len(h.takenOrder) > maxTakenSubs {
+			delete(h.taken, h.takenOrder[0])
+			h.takenOrder = h.takenOrder[1:]
+		}
+	}
+
+	return out
+}
+
+func newEthSubCompare(ctx context.Context,
+	vAPI v1.FullNode,
+	lAPI lapi.FullNode,
+	vSub, lSub *ethSubHandler,
+	window int,
+) *ethSubCompare {
+	return &ethSubCompare{
+		ctx:    ctx,
+		vAPI:   vAPI,
+		lAPI:   lAPI,
+		vSub:   vSub,
+		lSub:   lSub,
+		window: window,
+	}
+}
+
+// ethSubCompare subscribes newHeads and logs on both nodes at once, and compares the notifications
+// received in every window of epochs.
+type ethSubCompare struct {
+	ctx context.Context
+
+	vAPI v1.FullNode
+	lAPI lapi.FullNode
+
+	vSub *ethSubHandler
+	lSub *ethSubHandler
+
+	window int
+}
+
+func (c *ethSubCompare) start() {
+	for {
+		if err := c.compareWindow(); err != nil {
+			logrus.Errorf("compare eth subscription failed: %v", err)
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(reconnectMinDelay):
+		}
+	}
+}
+
+type ethSubIDs struct {
+	kind string
+	vID  types.EthSubscriptionID
+	lID  ethtypes.EthSubscriptionID
+}
+
+func (c *ethSubCompare) compareWindow() error {
+	subs := make([]ethSubIDs, 0, len(ethSubKinds))
+	// drop the notifications of the subscriptions not compared
+	defer func() {
+		for _, sub := range subs {
+			c.unsubscribe(sub)
+			c.vSub.take(sub.vID)
+			c.lSub.take(types.EthSubscriptionID(sub.lID))
+		}
+	}()
+	for _, kind := range ethSubKinds {
+		sub, err := c.subscribe(kind)
+		if err != nil {
+			return err
+		}
+		subs = append(subs, sub)
+	}
+	logrus.Infof("subscribed eth %v, compare after %d epochs", ethSubKinds, c.window)
+
+	select {
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	case <-time.After(time.Duration(c.window) * time.Duration(builtin.EpochDurationSeconds) * time.Second):
+	}
+
+	for _, sub := range subs {
+		c.unsubscribe(sub)
+	}
+	for _, sub := range subs {
+		v, err := parseEthSubNotifications(sub.kind, c.vSub.take(sub.vID))
+		if err != nil {
+			return fmt.Errorf("parse venus %s notifications: %v", sub.kind, err)
+		}
+		l, err := parseEthSubNotifications(sub.kind, c.lSub.take(types.EthSubscriptionID(sub.lID)))
+		if err != nil {
+			return fmt.Errorf("parse lotus %s notifications: %v", sub.kind, err)
+		}
+
+		diffs := diffEthSubNotifications(v, l)
+		for _, diff := range diffs {
+			logrus.Errorf("compare eth subscription %s failed: %s", sub.kind, diff)
+		}
+		if len(diffs) == 0 {
+			logrus.Infof("compare eth subscription %s success, venus %d, lotus %d", sub.kind, len(v), len(l))
+		}
+	}
+	subs = nil
+
+	return nil
+}
+
+func (c *ethSubCompare) subscribe(kind string) (ethSubIDs, error) {
+	params, err := json.Marshal([]string{kind})
+	if err != nil {
+		return ethSubIDs{}, err
+	}
+
+	sub := ethSubIDs{kind: kind}
+	var vErr, lErr error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		sub.vID, vErr = c.vAPI.EthSubscribe(c.ctx, params)
+	}()
+	go func() {
+		defer wg.Done()
+		sub.lID, lErr = c.lAPI.EthSubscribe(c.ctx, params)
+	}()
+	wg.Wait()
+
+	if vErr != nil || lErr != nil {
+		if vErr == nil {
+			_, _ = c.vAPI.EthUnsubscribe(c.ctx, sub.vID)
+			c.vSub.take(sub.vID)
+		}
+		if lErr == nil {
+			_, _ = c.lAPI.EthUnsubscribe(c.ctx, sub.lID)
+			c.lSub.take(types.EthSubscriptionID(sub.lID))
+		}
+		return ethSubIDs{}, fmt.Errorf("subscribe %s, venus error: %v, lotus error: %v", kind, vErr, lErr)
+	}
+
+	return sub, nil
+}
+
+func (c *ethSubCompare) unsubscribe(sub ethSubIDs) {
+	if _, err := c.vAPI.EthUnsubscribe(c.ctx, sub.vID); err != nil {
+		logrus.Debugf("venus unsubscribe %s failed: %v", sub.kind, err)
+	}
+	if _, err := c.lAPI.EthUnsubscribe(c.ctx, sub.lID); err != nil {
+		logrus.Debugf("lotus unsubscribe %s failed: %v", sub.kind, err)
+	}
+}
+
+type ethSubNotification struct {
+	blockHash string
+	height    uint64
+	// normalized json of the notification
+	data string
+}
+
+// parseEthSubNotifications gets the block of newHeads and logs notifications, and normalizes the json.
+func parseEthSubNotifications(kind string, raws []json.RawMessage) ([]ethSubNotification, error) {
+	out := make([]ethSubNotification, 0, len(raws))
+	for _, raw := range raws {
+		var blk struct {
+			Hash        types.EthHash   `json:"hash"`
+			Number      types.EthUint64 `json:"number"`
+			BlockHash   types.EthHash   `json:"blockHash"`
+			BlockNumber types.EthUint64 `json:"blockNumber"`
+		}
+		if err := json.Unmarshal(raw, &blk); err != nil {
+			return nil, err
+		}
+		n := ethSubNotification{blockHash: blk.Hash.String(), height: uint64(blk.Number)}
+		if kind == ethSubLogs {
+			n = ethSubNotification{blockHash: blk.BlockHash.String(), height: uint64(blk.BlockNumber)}
+		}
+
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		n.data = string(data)
+		out = append(out, n)
+	}
+
+	return out, nil
+}
+
+// diffEthSubNotifications aligns the notifications by block hash, and returns the missing, extra, out of order
+// and different ones of lotus compared to venus. The blocks at the edges of the window may only be notified by
+// one node, so only the heights notified by both nodes are compared.
+func diffEthSubNotifications(vNotifs, lNotifs []ethSubNotification) []string {
+	if len(vNotifs) == 0 && len(lNotifs) == 0 {
+		return nil
+	}
+	low, high := uint64(0), ^uint64(0)
+	if len(vNotifs) > 0 && len(lNotifs) > 0 {
+		vLow, vHigh := heightRange(vNotifs)
+		lLow, lHigh := heightRange(lNotifs)
+		if vLow > lLow {
+			low = vLow
+		} else {
+			low = lLow
+		}
+		if vHigh < lHigh {
+			high = vHigh
+		} else {
+			high = lHigh
+		}
+	}
+
+	group := func(notifs []ethSubNotification) ([]string, map[string][]ethSubNotification) {
+		var order []string
+		blocks := make(map[string][]ethSubNotification)
+		for _, n := range notifs {
+			if n.height < low || n.height > high {
+				continue
+			}
+			if _, ok := blocks[n.blockHash]; !ok {
+				order = append(order, n.blockHash)
+			}
+			blocks[n.blockHash] = append(blocks[n.blockHash], n)
+		}
+		return order, blocks
+	}
+	vOrder, vBlocks := group(vNotifs)
+	lOrder, lBlocks := group(lNotifs)
+
+	var diffs []string
+	var vCommon, lCommon []string
+	for _, hash := range vOrder {
+		if _, ok := lBlocks[hash]; !ok {
+			diffs = append(diffs, fmt.Sprintf("missing block %s at height %d in lotus", hash, vBlocks[hash][0].height))
+			continue
+		}
+		vCommon = append(vCommon, hash)
+	}
+	for _, hash := range lOrder {
+		if _, ok := vBlocks[hash]; !ok {
+			diffs = append(diffs, fmt.Sprintf("extra block %s at height %d in lotus", hash, lBlocks[hash][0].height))
+			continue
+		}
+		lCommon = append(lCommon, hash)
+	}
+
+	for i := range vCommon {
+		if vCommon[i] != lCommon[i] {
+			diffs = append(diffs, fmt.Sprintf("out of order at %d, venus %s, lotus %s", i, vCommon[i], lCommon[i]))
+			break
+		}
+	}
+
+	for _, hash := range vCommon {
+		v, l := vBlocks[hash], lBlocks[hash]
+		if len(v) != len(l) {
+			diffs = append(diffs, fmt.Sprintf("block %s notifications length %d != %d", hash, len(v), len(l)))
+			continue
+		}
+		for i := range v {
+			if v[i].data != l[i].data {
+				diffs = append(diffs, fmt.Sprintf("block %s notification %d not match %s != %s", hash, i, v[i].data, l[i].data))
+				break
+			}
+		}
+	}
+
+	return diffs
+}
+
+func heightRange(notifs []ethSubNotification) (uint64, uint64) {
+	low, high := notifs[0].height, notifs[0].height
+	for _, n := range notifs {
+		if n.height < low {
+			low = n.height
+		}
+		if n.height > high {
+			high = n.height
+		}
+	}
+
+	return low, high
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEthSubHandler(t *testing.T) {
+	h := newEthSubHandler()
+	id := types.EthSubscriptionID{1}
+	resp, err := json.Marshal(types.EthSubscriptionResponse{SubscriptionID: id, Result: map[string]string{"hash": "0x01"}})
+	require.NoError(t, err)
+
+	require.NoError(t, h.EthSubscription(context.Background(), resp))
+	require.NoError(t, h.EthSubscription(context.Background(), resp))
+	assert.Len(t, h.take(id), 2)
+	assert.Len(t, h.take(id), 0)
+
+	// arrived after taken
+	require.NoError(t, h.EthSubscription(context.Background(), resp))
+	assert.Empty(t, h.received)
+
+	for i := 0; i <= maxTakenSubs; i++ {
+		h.take(types.EthSubscriptionID{byte(i), 1})
+	}
+	assert.Len(t, h.taken, maxTakenSubs)
+	assert.Len(t, h.takenOrder, maxTakenSubs)
+}
+
+func TestDiffEthSubNotifications(t *testing.T) {
+	n := func(hash string, height uint64, data string) ethSubNotification {
+		return ethSubNotification{blockHash: hash, height: height, data: data}
+	}
+
+	v := []ethSubNotification{n("a", 1, "1"), n("b", 2, "2"), n("c", 3, "3")}
+	assert.Len(t, diffEthSubNotifications(v, v), 0)
+
+	// edges of the window only notified by one node
+	assert.Len(t, diffEthSubNotifications(v, v[1:]), 0)
+	assert.Len(t, diffEthSubNotifications(v[:2], v), 0)
+
+	// missing and extra
+	l := []ethSubNotification{n("a", 1, "1"), n("x", 2, "2"), n("c", 3, "3")}
+	assert.Len(t, diffEthSubNotifications(v, l), 2)
+
+	// out of order
+	l = []ethSubNotification{n("a", 1, "1"), n("c", 3, "3"), n("b", 2, "2")}
+	assert.Len(t, diffEthSubNotifications(v, l), 1)
+
+	// different
+	l = []ethSubNotification{n("a", 1, "1"), n("b", 2, "22"), n("c", 3, "3")}
+	assert.Len(t, diffEthSubNotifications(v, l), 1)
+
+	// lotus received nothing
+	assert.Len(t, diffEthSubNotifications(v, nil), 3)
+}
+
+func TestParseEthSubNotifications(t *testing.T) {
+	raws := []json.RawMessage{[]byte(`{"number":"0xa","hash":"0x0000000000000000000000000000000000000000000000000000000000000001"}`)}
+	notifs, err := parseEthSubNotifications(ethSubNewHeads, raws)
+	require.NoError(t, err)
+	require.Len(t, notifs, 1)
+	assert.Equal(t, uint64(10), notifs[0].height)
+	assert.Equal(t, `{"hash":"0x0000000000000000000000000000000000000000000000000000000000000001","number":"0xa"}`, notifs[0].data)
+
+	raws = []json.RawMessage{[]byte(`{"blockNumber":"0xb","blockHash":"0x0000000000000000000000000000000000000000000000000000000000000002"}`)}
+	notifs, err = parseEthSubNotifications(ethSubLogs, raws)
+	require.NoError(t, err)
+	require.Len(t, notifs, 1)
+	assert.Equal(t, uint64(11), notifs[0].height)
+	assert.Equal(t, types.EthHash{31: 2}.String(), notifs[0].blockHash)
+}
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/filecoin-project/go-jsonrpc"
+	"github.com/filecoin-project/venus/venus-shared/api"
+	"github.com/filecoin-project/venus/venus-shared/types"
+)
+
+// the trace types of EthTraceReplayBlockTransactions, only trace is supported by the nodes
+var ethTraceTypes = []string{"trace"}
+
+// ethTraceAPI calls the eth trace apis by json rpc, the venus and lotus apis this tool depends on not provide them.
+type ethTraceAPI struct {
+	Internal struct {
+		EthTraceBlock                   func(ctx context.Context, blkNum string) ([]*ethBlockTrace, error)
+		EthTraceReplayBlockTransactions func(ctx context.Context, blkNum string, traceTypes []string) ([]*ethTraceReplayBlockTransaction, error)
+	}
+}
+
+func (a *ethTraceAPI) EthTraceBlock(ctx context.Context, blkNum string) ([]*ethBlockTrace, error) {
+	return a.Internal.EthTraceBlock(ctx, blkNum)
+}
+
+func (a *ethTraceAPI) EthTraceReplayBlockTransactions(ctx context.Context, blkNum string, traceTypes []string) ([]*ethTraceReplayBlockTransaction, error) {
+	return a.Internal.EthTraceReplayBlockTransactions(ctx, blkNum, traceTypes)
+}
+
+func newEthTraceRPC(ctx context.Context, url, token string, opts ...jsonrpc.Option) (*ethTraceAPI, jsonrpc.ClientCloser, error) {
+	apiInfo := api.NewAPIInfo(url, token)
+	endpoint, err := apiInfo.DialArgs("v1")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res ethTraceAPI
+	closer, err := jsonrpc.NewMergeClient(ctx, endpoint, "Filecoin", []interface{}{&res.Internal}, apiInfo.AuthHeader(), opts...)
+
+	return &res, closer, err
+}
+
+// ethTrace is a call in the flat trace list, its position in the call tree is traceAddress.
+// Action and result are kept as json objects, so that both nodes are decoded in the same way.
+type ethTrace struct {
+	Type         string                 `json:"type"`
+	Action       map[string]interface{} `json:"action"`
+	Result       map[string]interface{} `json:"result"`
+	Error        string                 `json:"error,omitempty"`
+	Subtraces    int                    `json:"subtraces"`
+	TraceAddress []int                  `json:"traceAddress"`
+}
+
+type ethBlockTrace struct {
+	*ethTrace
+	BlockHash           types.EthHash `json:"blockHash"`
+	BlockNumber         int64         `json:"blockNumber"`
+	TransactionHash     types.EthHash `json:"transactionHash"`
+	TransactionPosition int           `json:"transactionPosition"`
+}
+
+type ethTraceReplayBlockTransaction struct {
+	Output          types.EthBytes `json:"output"`
+	StateDiff       *string        `json:"stateDiff"`
+	Trace           []*ethTrace    `json:"trace"`
+	TransactionHash types.EthHash  `json:"transactionHash"`
+	VMTrace         *string        `json:"vmTrace"`
+}
+
+// ethTraceNode is a call with its subcalls, built from the flat traces.
+type ethTraceNode struct {
+	trace    *ethTrace
+	subcalls []*ethTraceNode
+}
+
+func (ac *apiCompare) CompareEthTraceBlock() error {
+	blkParam := toBlkParam(ac.dp.currentTS.Height())
+
+	return ac.sendTraceAndWait(ethTraceBlock, toInterface(ac.ctx, blkParam), withResultCheck(func(r1, r2 interface{}) error {
+		o1, _ := r1.([]*ethBlockTrace)
+		o2, _ := r2.([]*ethBlockTrace)
+		return checkEthTraceBlock(o1, o2)
+	}))
+}
+
+func (ac *apiCompare) CompareEthTraceReplayBlockTransactions() error {
+	blkParam := toBlkParam(ac.dp.currentTS.Height())
+
+	return ac.sendTraceAndWait(ethTraceReplayBlockTransactions, toInterface(ac.ctx, blkParam, ethTraceTypes), withResultCheck(func(r1, r2 interface{}) error {
+		o1, _ := r1.([]*ethTraceReplayBlockTransaction)
+		o2, _ := r2.([]*ethTraceReplayBlockTransaction)
+		return checkEthTraceReplay(o1, o2)
+	}))
+}
+
+func checkEthTraceBlock(vTraces, lTraces []*ethBlockTrace) error {
+	if len(vTraces) != len(lTraces) {
+		return fmt.Errorf("traces length %d != %d", len(vTraces), len(lTraces))
+	}
+
+	vFlat := make([]*ethTrace, 0, len(vTraces))
+	lFlat := make([]*ethTrace, 0, len(lTraces))
+	for i, v := range vTraces {
+		l := lTraces[i]
+		if v.BlockHash != l.BlockHash || v.BlockNumber != l.BlockNumber {
+			return fmt.Errorf("trace %d block %s %d != %s %d", i, v.BlockHash, v.BlockNumber, l.BlockHash, l.BlockNumber)
+		}
+		if v.TransactionHash != l.TransactionHash || v.TransactionPosition != l.TransactionPosition {
+			return fmt.Errorf("trace %d transaction %s %d != %s %d", i, v.TransactionHash, v.TransactionPosition,
+				l.TransactionHash, l.TransactionPosition)
+		}
+		vFlat = append(vFlat, v.ethTrace)
+		lFlat = append(lFlat, l.ethTrace)
+	}
+
+	return checkEthTraces(vFlat, lFlat)
+}
+
+func checkEthTraceReplay(vTxs, lTxs []*ethTraceReplayBlockTransaction) error {
+	if len(vTxs) != len(lTxs) {
+		return fmt.Errorf("transactions length %d != %d", len(vTxs), len(lTxs))
+	}
+
+	for i, v := range vTxs {
+		l := lTxs[i]
+		if v.TransactionHash != l.TransactionHash {
+			return fmt.Errorf("transaction %d hash %s != %s", i, v.TransactionHash, l.TransactionHash)
+		}
+		if err := checkByJSON(v.Output, l.Output); err != nil {
+			return fmt.Errorf("transaction %s output %w", v.TransactionHash, err)
+		}
+		if err := checkEthTraces(v.Trace, l.Trace); err != nil {
+			return fmt.Errorf("transaction %s %w", v.TransactionHash, err)
+		}
+	}
+
+	return nil
+}
+
+// checkEthTraces builds the call trees from the flat traces, and compares them recursively.
+func checkEthTraces(vTraces, lTraces []*ethTrace) error {
+	vRoots, err := buildEthTraceTree(vTraces)
+	if err != nil {
+		return fmt.Errorf("venus %w", err)
+	}
+	lRoots, err := buildEthTraceTree(lTraces)
+	if err != nil {
+		return fmt.Errorf("lotus %w", err)
+	}
+	if len(vRoots) != len(lRoots) {
+		return fmt.Errorf("root traces %d != %d", len(vRoots), len(lRoots))
+	}
+
+	for i := range vRoots {
+		if err := checkEthTrace(vRoots[i], lRoots[i]); err != nil {
+			return fmt.Errorf("trace %d %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func checkEthTrace(vNode, lNode *ethTraceNode) error {
+	v, l := vNode.trace, lNode.trace
+	if v.Type != l.Type {
+		return fmt.Errorf("type not match %s != %s", v.Type, l.Type)
+	}
+	if v.Error != l.Error {
+		return fmt.Errorf("error not match %s != %s", v.Error, l.Error)
+	}
+	if err := checkByJSON(v.Action, l.Action); err != nil {
+		return fmt.Errorf("action %v", err)
+	}
+	if err := checkByJSON(v.Result, l.Result); err != nil {
+		return fmt.Errorf("result %v", err)
+	}
+	if v.Subtraces != l.Subtraces || len(vNode.subcalls) != len(lNode.subcalls) {
+		return fmt.Errorf("subtraces %d != %d", len(vNode.subcalls), len(lNode.subcalls))
+	}
+
+	for i := range vNode.subcalls {
+		if err := checkEthTrace(vNode.subcalls[i], lNode.subcalls[i]); err != nil {
+			return fmt.Errorf("subtraces %v", err)
+		}
+	}
+
+	return nil
+}
+
+// buildEthTraceTree builds the call trees from the flat traces in depth first order, every root trace
+// has an empty traceAddress.
+func buildEthTraceTree(traces []*ethTrace) ([]*ethTraceNode, error) {
+	var roots []*ethTraceNode
+	for i, t := range traces {
+		node := &ethTraceNode{trace: t}
+		if len(t.TraceAddress) == 0 {
+			roots = append(roots, node)
+			continue
+		}
+		if len(roots) == 0 {
+			return nil, fmt.Errorf("trace %d %v has no root", i, t.TraceAddress)
+		}
+
+		parent := roots[len(roots)-1]
+		last := len(t.TraceAddress) - 1
+		for _, idx := range t.TraceAddress[:last] {
+			if idx < 0 || idx >= len(parent.subcalls) {
+				return nil, fmt.Errorf("trace %d %v has no parent", i, t.TraceAddress)
+			}
+			parent = parent.subcalls[idx]
+		}
+		if t.TraceAddress[last] != len(parent.subcalls) {
+			return nil, fmt.Errorf("trace %d %v out of order", i, t.TraceAddress)
+		}
+		parent.subcalls = append(parent.subcalls, node)
+	}
+
+	return roots, nil
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildEthTraceTree(t *testing.T) {
+	traces := []*ethTrace{
+		{Type: "call", Subtraces: 2},
+		{Type: "call", TraceAddress: []int{0}, Subtraces: 1},
+		{Type: "call", TraceAddress: []int{0, 0}},
+		{Type: "create", TraceAddress: []int{1}},
+		{Type: "call"},
+	}
+	roots, err := buildEthTraceTree(traces)
+	assert.NoError(t, err)
+	assert.Len(t, roots, 2)
+	assert.Len(t, roots[0].subcalls, 2)
+	assert.Len(t, roots[0].subcalls[0].subcalls, 1)
+	assert.Equal(t, "create", roots[0].subcalls[1].trace.Type)
+	assert.Empty(t, roots[1].subcalls)
+
+	_, err = buildEthTraceTree([]*ethTrace{{TraceAddress: []int{0}}})
+	assert.Error(t, err)
+	_, err = buildEthTraceTree([]*ethTrace{{}, {TraceAddress: []int{1}}})
+	assert.Error(t, err)
+	_, err = buildEthTraceTree([]*ethTrace{{}, {TraceAddress: []int{1, 0}}})
+	assert.Error(t, err)
+}
+
+func TestCheckEthTraces(t *testing.T) {
+	newTraces := func(to string) []*ethTrace {
+		return []*ethTrace{
+			{Type: "call", Action: map[string]interface{}{"to": "0x01"}, Subtraces: 1},
+			{Type: "call", Action: map[string]interface{}{"to": to}, TraceAddress: []int{0}},
+		}
+	}
+
+	assert.NoError(t, checkEthTraces(newTraces("0x02"), newTraces("0x02")))
+	assert.Error(t, checkEthTraces(newTraces("0x02"), newTraces("0x03")))
+	assert.Error(t, checkEthTraces(newTraces("0x02"), newTraces("0x02")[:1]))
+}
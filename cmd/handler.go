@@ -4,19 +4,19 @@ import (
 	"context"
 	"fmt"
 	"reflect"
-	"sync"
 
-	lapi "github.com/filecoin-project/lotus/api"
 	"github.com/filecoin-project/lotus/chain/types/ethtypes"
-	vapi "github.com/filecoin-project/venus/venus-shared/api/chain/v1"
 	"github.com/filecoin-project/venus/venus-shared/types"
 	"github.com/sirupsen/logrus"
 )
 
-func newHandler(ctx context.Context, vAPI vapi.FullNode, lAPI lapi.FullNode, concurrency int) *handler {
+// newHandler compares the methods of vAPI and lAPI, usually they are the full node apis of venus and lotus.
+// The handlers sharing controlCh share the limit of concurrent calls.
+func newHandler(ctx context.Context, vAPI, lAPI interface{}, controlCh chan struct{}, timeouts *callTimeouts) *handler {
 	h := &handler{
-		ctx:         ctx,
-		concurrency: concurrency,
+		ctx:       ctx,
+		controlCh: controlCh,
+		timeouts:  timeouts,
 
 		vAPI: apiInfo{
 			rv: reflect.ValueOf(vAPI),
@@ -36,8 +36,9 @@ func newHandler(ctx context.Context, vAPI vapi.FullNode, lAPI lapi.FullNode, con
 }
 
 type handler struct {
-	ctx         context.Context
-	concurrency int
+	ctx       context.Context
+	controlCh chan struct{}
+	timeouts  *callTimeouts
 
 	vAPI apiInfo
 	lAPI apiInfo
@@ -51,9 +52,8 @@ type apiInfo struct {
 }
 
 func (h *handler) start() {
-	controlCh := make(chan struct{}, h.concurrency)
 	done := func() {
-		<-controlCh
+		<-h.controlCh
 	}
 	for {
 		select {
@@ -61,11 +61,12 @@ func (h *handler) start() {
 			logrus.Warn("context done, stop handler req")
 			return
 		case r := <-h.receiver:
-			controlCh <- struct{}{}
+			h.controlCh <- struct{}{}
 			go func() {
 				defer done()
 
-				r.err <- h.call(r)
+				err := h.call(r)
+				r.err <- err
 				close(r.err)
 			}()
 		}
@@ -86,49 +87,98 @@ func (h *handler) call(r *req) error {
 		return fmt.Errorf("not found method %s", r.methodName)
 	}
 
-	inParams := make([]reflect.Value, 0, len(r.in))
-	inParams2 := make([]reflect.Value, 0, len(r.in))
-	for i, param := range r.in {
-		v := reflect.ValueOf(param)
-		inParams = append(inParams, v)
-		// The first parameter is usually context.Context
-		if i == 0 {
-			inParams2 = append(inParams2, v)
-			continue
-		}
-		inParams2 = append(inParams2, reflect.ValueOf(tryConvertParam(param)))
-	}
+	timeout := h.timeouts.get(r.methodName)
+	ctx, cancel := context.WithTimeout(h.ctx, timeout)
+	defer cancel()
 
-	var vRes, lRes []reflect.Value
-	wg := sync.WaitGroup{}
-	wg.Add(2)
+	lotusIn := r.in
+	if r.lotusIn != nil {
+		lotusIn = r.lotusIn
+	}
+	inParams := toReflectParams(ctx, r.in, false)
+	inParams2 := toReflectParams(ctx, lotusIn, true)
 
+	vCh := make(chan []reflect.Value, 1)
+	lCh := make(chan []reflect.Value, 1)
 	go func() {
-		defer wg.Done()
-		vRes = vm.Func.Call(append([]reflect.Value{h.vAPI.rv}, inParams...))
+		out := vm.Func.Call(append([]reflect.Value{h.vAPI.rv}, inParams...))
+		r.notifyResult("venus", out)
+		vCh <- out
 	}()
 	go func() {
-		defer wg.Done()
-		lRes = lm.Func.Call(append([]reflect.Value{h.lAPI.rv}, inParams2...))
+		out := lm.Func.Call(append([]reflect.Value{h.lAPI.rv}, inParams2...))
+		r.notifyResult("lotus", out)
+		lCh <- out
 	}()
-	wg.Wait()
+
+	var vRes, lRes []reflect.Value
+	for vRes == nil || lRes == nil {
+		select {
+		case vRes = <-vCh:
+		case lRes = <-lCh:
+		case <-ctx.Done():
+			if h.ctx.Err() != nil {
+				return h.ctx.Err()
+			}
+			// cancel the in-flight call, the node which not answered will see the context done
+			cancel()
+			e := &timeoutError{method: r.methodName, timeout: timeout}
+			if vRes == nil {
+				e.nodes = append(e.nodes, "venus")
+			}
+			if lRes == nil {
+				e.nodes = append(e.nodes, "lotus")
+			}
+			return e
+		}
+	}
 
 	if len(vRes) == 0 {
 		return h.handleError(vRes[0], lRes[0])
 	}
 
 	if len(vRes) == 2 {
+		vErr, _ := vRes[1].Interface().(error)
+		lErr, _ := lRes[1].Interface().(error)
+		if vErr != nil && lErr != nil && r.errorChecker != nil {
+			return r.divergence(r.errorChecker(vErr, lErr))
+		}
 		if err := h.handleError(vRes[1], lRes[1]); err != nil && !r.expectCallAPIError {
-			return err
+			// both nodes failed is not a divergence, eg: the nodes are restarting
+			if vErr != nil && lErr != nil {
+				return err
+			}
+			return r.divergence(err)
 		}
 	}
 	logrus.Tracef("call %s result: \n%+v\n%+v", r.methodName, vRes[0].Interface(), lRes[0].Interface())
 
 	if r.resultChecker != nil {
-		return r.resultChecker(vRes[0].Interface(), lRes[0].Interface())
+		return r.divergence(r.resultChecker(vRes[0].Interface(), lRes[0].Interface()))
+	}
+
+	return r.divergence(checkByJSON(vRes[0].Interface(), lRes[0].Interface()))
+}
+
+// toReflectParams replaces the first context.Context parameter with ctx, and converts venus types to lotus types if convert is true.
+func toReflectParams(ctx context.Context, in []interface{}, convert bool) []reflect.Value {
+	params := make([]reflect.Value, 0, len(in))
+	for i, param := range in {
+		// The first parameter is usually context.Context
+		if i == 0 {
+			if _, ok := param.(context.Context); ok {
+				param = ctx
+			}
+			params = append(params, reflect.ValueOf(param))
+			continue
+		}
+		if convert {
+			param = tryConvertParam(param)
+		}
+		params = append(params, reflect.ValueOf(param))
 	}
 
-	return checkByJSON(vRes[0].Interface(), lRes[0].Interface())
+	return params
 }
 
 // todo: not check each param
@@ -169,6 +219,22 @@ func tryConvertParam(param interface{}) interface{} {
 	if ok {
 		return toLotusEthMessageMatch(msgMatch)
 	}
+	spec, ok := param.(*types.MessageSendSpec)
+	if ok {
+		return toLotusMessageSendSpec(spec)
+	}
+	protos, ok := param.([]*types.MessagePrototype)
+	if ok {
+		return toLotusMessagePrototypes(protos)
+	}
+	filterSpec, ok := param.(*types.EthFilterSpec)
+	if ok {
+		return toLotusEthFilterSpec(filterSpec)
+	}
+	filterID, ok := param.(types.EthFilterID)
+	if ok {
+		return ethtypes.EthFilterID(filterID)
+	}
 
 	return param
 }
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	pinHeadTimes    = 5
+	pinHeadInterval = 3 * time.Second
+	// about two epochs
+	newHeadTimeout = 75 * time.Second
+)
+
+// withPinnedHead runs f when both nodes are at the same head, and runs again if the head changed during f,
+// so that the apis depend on the head, eg: latest, are compared at the same head.
+func (ac *apiCompare) withPinnedHead(f func() error) error {
+	var lastErr error
+	for i := 0; i < pinHeadTimes; i++ {
+		if i > 0 {
+			select {
+			case <-ac.ctx.Done():
+				return ac.ctx.Err()
+			case <-time.After(pinHeadInterval):
+			}
+		}
+
+		head, err := ac.sameHead()
+		if err != nil {
+			lastErr = err
+			logrus.Debugf("pin head failed: %v", err)
+			continue
+		}
+
+		err = f()
+
+		after, hErr := ac.sameHead()
+		if hErr != nil || !after.Equals(head) {
+			lastErr = fmt.Errorf("head changed from %v during compare", head)
+			logrus.Debugf("head changed from %v during compare, retry", head)
+			continue
+		}
+
+		return err
+	}
+
+	return fmt.Errorf("failed to pin head after %d times: %w", pinHeadTimes, lastErr)
+}
+
+// sameHead returns the head key when venus and lotus have the same head.
+func (ac *apiCompare) sameHead() (types.TipSetKey, error) {
+	ctx, cancel := context.WithTimeout(ac.ctx, ac.handler.timeouts.get(chainHead))
+	defer cancel()
+
+	vHead, err := ac.vAPI.ChainHead(ctx)
+	if err != nil {
+		return types.EmptyTSK, err
+	}
+	lHead, err := ac.lAPI.ChainHead(ctx)
+	if err != nil {
+		return types.EmptyTSK, err
+	}
+	if !vHead.Key().Equals(types.NewTipSetKey(lHead.Cids()...)) {
+		return types.EmptyTSK, fmt.Errorf("head not match, venus: %d %v, lotus: %d %v",
+			vHead.Height(), vHead.Key(), lHead.Height(), lHead.Key())
+	}
+
+	return vHead.Key(), nil
+}
+
+// waitNewHead waits until the heads of both nodes are higher than h.
+func (ac *apiCompare) waitNewHead(h abi.ChainEpoch) error {
+	ctx, cancel := context.WithTimeout(ac.ctx, newHeadTimeout)
+	defer cancel()
+
+	for {
+		vHead, err := ac.vAPI.ChainHead(ctx)
+		if err != nil {
+			return err
+		}
+		lHead, err := ac.lAPI.ChainHead(ctx)
+		if err != nil {
+			return err
+		}
+		if vHead.Height() > h && lHead.Height() > h {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait new head after %d: %w", h, ctx.Err())
+		case <-time.After(pinHeadInterval):
+		}
+	}
+}
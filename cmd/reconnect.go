@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/filecoin-project/go-jsonrpc"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	reconnectMinDelay   = 500 * time.Millisecond
+	reconnectMaxDelay   = 30 * time.Second
+	healthCheckInterval = 30 * time.Second
+	healthCheckTimeout  = 10 * time.Second
+)
+
+// rpcOptions let the rpc client reconnect with backoff when the connection drops.
+func rpcOptions() []jsonrpc.Option {
+	return []jsonrpc.Option{
+		jsonrpc.WithReconnectBackoff(reconnectMinDelay, reconnectMaxDelay),
+	}
+}
+
+type backoff struct {
+	minDelay time.Duration
+	maxDelay time.Duration
+}
+
+func newBackoff() *backoff {
+	return &backoff{
+		minDelay: reconnectMinDelay,
+		maxDelay: reconnectMaxDelay,
+	}
+}
+
+func (b *backoff) next(attempt int) time.Duration {
+	delay := b.minDelay
+	for i := 0; i < attempt; i++ {
+		delay *= 2
+		if delay > b.maxDelay || delay <= 0 {
+			return b.maxDelay
+		}
+	}
+
+	return delay
+}
+
+// retry calls f until it succeeds or the context is done, sleeping with backoff between attempts.
+func retry(ctx context.Context, name string, f func() error) error {
+	b := newBackoff()
+	for attempt := 0; ; attempt++ {
+		err := f()
+		if err == nil {
+			if attempt > 0 {
+				logrus.Infof("%s recovered after %d attempts", name, attempt)
+			}
+			return nil
+		}
+
+		delay := b.next(attempt)
+		logrus.Warnf("%s failed: %v, retry after %v", name, err, delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (mgr *compareMgr) checkHealth() error {
+	ctx, cancel := context.WithTimeout(mgr.ctx, healthCheckTimeout)
+	defer cancel()
+
+	if _, err := mgr.vAPI.ChainHead(ctx); err != nil {
+		return fmt.Errorf("venus unavailable: %v", err)
+	}
+	if _, err := mgr.lAPI.ChainHead(ctx); err != nil {
+		return fmt.Errorf("lotus unavailable: %v", err)
+	}
+
+	return nil
+}
+
+// onDisconnect is called when the connection to a node dropped, the node may have restarted.
+func (mgr *compareMgr) onDisconnect() {
+	atomic.AddInt64(&mgr.generation, 1)
+}
+
+// waitNodesReady blocks until both nodes answer.
+func (mgr *compareMgr) waitNodesReady() error {
+	return retry(mgr.ctx, "health check", mgr.checkHealth)
+}
+
+// healthLoop checks the connection of both nodes periodically, and records the recovered connections.
+func (mgr *compareMgr) healthLoop() {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	healthy := true
+	for {
+		select {
+		case <-mgr.ctx.Done():
+			return
+		case <-ticker.C:
+			err := mgr.checkHealth()
+			if err != nil && healthy {
+				logrus.Warnf("connection lost: %v", err)
+			} else if err == nil && !healthy {
+				logrus.Infof("connection recovered")
+				mgr.onDisconnect()
+			}
+			healthy = err == nil
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	b := &backoff{minDelay: time.Second, maxDelay: 10 * time.Second}
+
+	assert.Equal(t, time.Second, b.next(0))
+	assert.Equal(t, 4*time.Second, b.next(2))
+	assert.Equal(t, 10*time.Second, b.next(4))
+	assert.Equal(t, 10*time.Second, b.next(100))
+}
+
+func TestRetry(t *testing.T) {
+	count := 0
+	err := retry(context.Background(), "test", func() error {
+		count++
+		if count < 2 {
+			return fmt.Errorf("not ready")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = retry(ctx, "test", func() error {
+		return fmt.Errorf("not ready")
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -7,12 +7,14 @@ import (
 
 func newRegister() *register {
 	return &register{
-		funcs: map[string]rf{},
+		funcs:          map[string]rf{},
+		nullRoundFuncs: map[string]rf{},
 	}
 }
 
 type register struct {
-	funcs map[string]rf
+	funcs          map[string]rf
+	nullRoundFuncs map[string]rf
 }
 
 type rf func() error
@@ -29,12 +31,17 @@ func (r *register) registerAPICompare(ac *apiCompare) error {
 	for i := 0; i < rv.NumMethod(); i++ {
 		name := rt.Method(i).Name
 		m := rv.MethodByName(name)
-		if !strings.HasPrefix(name, methodPrefix) {
+		funcs := r.funcs
+		switch {
+		case strings.HasPrefix(name, methodPrefix):
+			name = strings.TrimPrefix(name, methodPrefix)
+		case strings.HasPrefix(name, nullRoundMethodPrefix):
+			funcs = r.nullRoundFuncs
+		default:
 			continue
 		}
 
-		name = strings.TrimPrefix(name, methodPrefix)
-		r.funcs[name] = func() error {
+		funcs[name] = func() error {
 			res := m.Call([]reflect.Value{})
 			if res[0].Interface() == nil {
 				return nil
@@ -13,3 +13,15 @@ func TestRegisterAPICompare(t *testing.T) {
 	assert.NoError(t, r.registerAPICompare(ac))
 	assert.GreaterOrEqual(t, len(r.funcs), 1)
 }
+
+func TestRegisterNullRoundFuncs(t *testing.T) {
+	ac := &apiCompare{}
+	r := newRegister()
+
+	assert.NoError(t, r.registerAPICompare(ac))
+	assert.Contains(t, r.nullRoundFuncs, "NullRoundEthGetBlockByNumber")
+	assert.NotContains(t, r.funcs, "NullRoundEthGetBlockByNumber")
+	for name := range r.funcs {
+		assert.NotContains(t, r.nullRoundFuncs, name)
+	}
+}
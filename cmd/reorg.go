@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/ipfs/go-cid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// reorgWindow is how many compared heights are remembered to detect reverts
+	reorgWindow = 900
+
+	forkWaitTimes    = 6
+	forkWaitInterval = 15 * time.Second
+	// recompareMaxWait bounds the total time waiting for nodes to converge when compare reverted heights again
+	recompareMaxWait = forkWaitTimes * forkWaitInterval
+)
+
+// forkError means venus and lotus picked different tipsets at the same height.
+type forkError struct {
+	height  abi.ChainEpoch
+	vHeight abi.ChainEpoch
+	lHeight abi.ChainEpoch
+	vKey    types.TipSetKey
+	lKey    types.TipSetKey
+	// the parent states of the tipsets picked by the nodes
+	vState     cid.Cid
+	lState     cid.Cid
+	sameParent bool
+}
+
+func (e *forkError) Error() string {
+	return fmt.Sprintf("nodes on different forks at height %d, venus: %d %v, lotus: %d %v",
+		e.height, e.vHeight, e.vKey, e.lHeight, e.lKey)
+}
+
+func newReorgTracker() *reorgTracker {
+	return &reorgTracker{
+		compared:  make(map[abi.ChainEpoch]types.TipSetKey),
+		recompare: make(map[abi.ChainEpoch]struct{}),
+	}
+}
+
+// reorgTracker records the compared tipsets, and finds out which heights need to be
+// compared again after their tipsets were reverted.
+type reorgTracker struct {
+	lk sync.Mutex
+
+	compared  map[abi.ChainEpoch]types.TipSetKey
+	recompare map[abi.ChainEpoch]struct{}
+	highest   abi.ChainEpoch
+}
+
+func (rt *reorgTracker) markCompared(ts *types.TipSet) {
+	rt.lk.Lock()
+	defer rt.lk.Unlock()
+
+	rt.compared[ts.Height()] = ts.Key()
+	delete(rt.recompare, ts.Height())
+	if ts.Height() > rt.highest {
+		rt.highest = ts.Height()
+	}
+	for h := range rt.compared {
+		if h < rt.highest-reorgWindow {
+			delete(rt.compared, h)
+		}
+	}
+}
+
+// revert returns true if the reverted tipset had been compared.
+func (rt *reorgTracker) revert(height abi.ChainEpoch, key types.TipSetKey) bool {
+	rt.lk.Lock()
+	defer rt.lk.Unlock()
+
+	compared, ok := rt.compared[height]
+	if !ok || !compared.Equals(key) {
+		return false
+	}
+	delete(rt.compared, height)
+	rt.recompare[height] = struct{}{}
+
+	return true
+}
+
+// popRecompare returns the heights need to compare again in ascending order.
+func (rt *reorgTracker) popRecompare() []abi.ChainEpoch {
+	rt.lk.Lock()
+	defer rt.lk.Unlock()
+
+	heights := make([]abi.ChainEpoch, 0, len(rt.recompare))
+	for h := range rt.recompare {
+		heights = append(heights, h)
+	}
+	rt.recompare = make(map[abi.ChainEpoch]struct{})
+	sort.Slice(heights, func(i, j int) bool {
+		return heights[i] < heights[j]
+	})
+
+	return heights
+}
+
+func (mgr *compareMgr) onRevert(node string, height abi.ChainEpoch, key types.TipSetKey) {
+	if !mgr.reorg.revert(height, key) {
+		return
+	}
+	logrus.Warnf("compared tipset %d %v reverted by %s, will compare again", height, key, node)
+
+	select {
+	case mgr.reverted <- struct{}{}:
+	default:
+	}
+}
+
+// lotusChainNotify tracks the reverts of lotus, the head is tracked by the chain notify of venus.
+func (mgr *compareMgr) lotusChainNotify() {
+	for {
+		var notifies <-chan []*lapi.HeadChange
+		err := retry(mgr.ctx, "subscribe lotus chain notify", func() error {
+			var err error
+			notifies, err = mgr.lAPI.ChainNotify(mgr.ctx)
+			return err
+		})
+		if err != nil {
+			logrus.Warnf("stop lotus chain notify: %v", err)
+			return
+		}
+
+		for notify := range notifies {
+			for _, change := range notify {
+				if types.HeadChangeType(change.Type) == types.HCRevert {
+					mgr.onRevert("lotus", change.Val.Height(), types.NewTipSetKey(change.Val.Cids()...))
+				}
+			}
+		}
+		// the channel is closed when the connection drops, subscribe again after reconnected
+		logrus.Warn("lotus chain notify channel closed, resubscribe")
+		mgr.onDisconnect()
+	}
+}
+
+// diffForkState diffs the parent states of the tipsets the nodes picked, which is where the state computed by
+// the nodes can differ. The state trees are walked in background, only one diff runs at a time.
+func (mgr *compareMgr) diffForkState(e *forkError) {
+	if !e.sameParent || e.vState == e.lState {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&mgr.diffing, 0, 1) {
+		return
+	}
+
+	go func() {
+		defer atomic.StoreInt32(&mgr.diffing, 0)
+
+		ctx, cancel := context.WithTimeout(mgr.ctx, stateDiffTimeout)
+		defer cancel()
+
+		diffs, err := newStateDiffer(ctx, mgr.vAPI, mgr.lAPI, e.vKey).diffStateRoots(ctx, e.vState, e.lState)
+		if err != nil {
+			logrus.Errorf("height %d, failed to diff state root %s and %s: %v", e.height, e.vState, e.lState, err)
+			return
+		}
+		logrus.Errorf("height %d, parent state %s != %s, %d actors differ", e.height, e.vState, e.lState, len(diffs))
+		for _, d := range diffs {
+			logrus.Errorf("height %d, %s", e.height, d)
+		}
+	}()
+}
+
+// recompareReverted compares the heights whose tipset changed, currentTS keeps unchanged.
+// The checkpoint is moved back to each compared height, and restored after all heights finished comparing,
+// so that a restart resumes from the reverted heights.
+func (mgr *compareMgr) recompareReverted() {
+	// a deep reorg should not block comparing new heights for a long time
+	waitCtx, cancel := context.WithTimeout(mgr.ctx, recompareMaxWait)
+	defer cancel()
+
+	cur := mgr.currentTS
+	finished := true
+	for _, h := range mgr.reorg.popRecompare() {
+		if h > cur.Height() {
+			continue
+		}
+		ts, timeouts, ok := mgr.compareHeight(waitCtx, h)
+		if !ok {
+			finished = false
+			continue
+		}
+		mgr.reorg.markCompared(ts)
+		if timeouts > 0 {
+			finished = false
+			continue
+		}
+		// heights above the checkpoint are compared again when resuming anyway
+		if finished && mgr.checkpointTS != nil && h <= mgr.checkpointTS.Height() {
+			mgr.saveCheckpoint(ts)
+		}
+	}
+
+	if finished && mgr.checkpointTS != nil {
+		mgr.saveCheckpoint(mgr.checkpointTS)
+	}
+}
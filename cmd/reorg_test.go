@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/venus/venus-shared/testutil"
+	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReorgTracker(t *testing.T) {
+	var ts, ts2 types.TipSet
+	testutil.Provide(t, &ts)
+	testutil.Provide(t, &ts2)
+
+	rt := newReorgTracker()
+	assert.False(t, rt.revert(ts.Height(), ts.Key()))
+
+	rt.markCompared(&ts)
+	if !ts2.Key().Equals(ts.Key()) {
+		assert.False(t, rt.revert(ts2.Height(), ts2.Key()))
+	}
+	assert.True(t, rt.revert(ts.Height(), ts.Key()))
+	// only need compare once
+	assert.False(t, rt.revert(ts.Height(), ts.Key()))
+
+	assert.Equal(t, []abi.ChainEpoch{ts.Height()}, rt.popRecompare())
+	assert.Len(t, rt.popRecompare(), 0)
+}
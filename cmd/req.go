@@ -1,5 +1,14 @@
 package cmd
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/sirupsen/logrus"
+)
+
 type req struct {
 	methodName string
 	in         []interface{}
@@ -8,6 +17,14 @@ type req struct {
 	// option
 	resultChecker      resultCheckFunc
 	expectCallAPIError bool
+	// called when both venus and lotus return error
+	errorChecker errorCheckFunc
+	// mismatch means consensus divergence
+	critical bool
+	// params for lotus, used when the params are node specific, eg: filter id
+	lotusIn []interface{}
+	// called with the result of each node which succeeded, even if the node answered after timeout
+	onResult func(node string, res interface{})
 }
 
 type reqOpt func(*req)
@@ -37,4 +54,79 @@ func withExpectCallAPIError() reqOpt {
 	}
 }
 
+func withCritical() reqOpt {
+	return func(r *req) {
+		r.critical = true
+	}
+}
+
+// withLotusParams calls lotus with in instead of the params of venus.
+func withLotusParams(in ...interface{}) reqOpt {
+	return func(r *req) {
+		r.lotusIn = in
+	}
+}
+
+// withErrorCheck compares the errors by f when both venus and lotus return error, eg: revert errors.
+func withErrorCheck(f errorCheckFunc) reqOpt {
+	return func(r *req) {
+		r.errorChecker = f
+	}
+}
+
+// withResultNotify calls f with the result of each node which succeeded, so that the side effects of a call,
+// eg: installed filter, can be cleaned up when the results are not compared.
+func withResultNotify(f func(node string, res interface{})) reqOpt {
+	return func(r *req) {
+		r.onResult = f
+	}
+}
+
+// notifyResult calls onResult if the call of node returned a result without error.
+func (r *req) notifyResult(node string, out []reflect.Value) {
+	if r.onResult == nil || len(out) != 2 || !out[1].IsNil() {
+		return
+	}
+	r.onResult(node, out[0].Interface())
+}
+
 type resultCheckFunc func(r1, r2 interface{}) error
+
+type errorCheckFunc func(vErr, lErr error) error
+
+// checkErrorPresence accepts that both venus and lotus return error, the messages of the two implementations
+// are not the same, so they are only logged.
+func checkErrorPresence(vErr, lErr error) error {
+	logrus.Debugf("venus and lotus all return error: %v, %v", vErr, lErr)
+	return nil
+}
+
+// criticalError means venus and lotus diverged on consensus critical data.
+type criticalError struct {
+	err error
+}
+
+func (e *criticalError) Error() string {
+	return fmt.Sprintf("critical: %v", e.err)
+}
+
+func (e *criticalError) Unwrap() error {
+	return e.err
+}
+
+// divergence marks err as critical if r is critical, err must be a divergence of venus and lotus.
+func (r *req) divergence(err error) error {
+	if r.critical {
+		return markCritical(err)
+	}
+	return err
+}
+
+// markCritical wraps err as criticalError, timeout and cancellation are not divergences so keep them as they are.
+func markCritical(err error) error {
+	var tErr *timeoutError
+	if err == nil || errors.As(err, &tErr) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return &criticalError{err: err}
+}
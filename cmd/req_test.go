@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkCritical(t *testing.T) {
+	assert.Nil(t, markCritical(nil))
+
+	var cErr *criticalError
+	assert.True(t, errors.As(markCritical(fmt.Errorf("not match")), &cErr))
+
+	tErr := &timeoutError{method: stateCirculatingSupply, nodes: []string{"lotus"}}
+	assert.Equal(t, tErr, markCritical(tErr))
+
+	ctxErr := fmt.Errorf("call failed: %w", context.Canceled)
+	assert.Equal(t, ctxErr, markCritical(ctxErr))
+
+	err := fmt.Errorf("not match")
+	assert.Equal(t, err, newReq(stateCirculatingSupply, nil).divergence(err))
+	assert.True(t, errors.As(newReq(stateCirculatingSupply, nil, withCritical()).divergence(err), &cErr))
+}
+
+func TestNotifyResult(t *testing.T) {
+	var got []interface{}
+	r := newReq(ethNewFilter, nil, withResultNotify(func(node string, res interface{}) {
+		got = append(got, res)
+	}))
+	errType := reflect.TypeOf((*error)(nil)).Elem()
+
+	r.notifyResult("venus", []reflect.Value{reflect.ValueOf(1), reflect.Zero(errType)})
+	r.notifyResult("lotus", []reflect.Value{reflect.ValueOf(2), reflect.ValueOf(errors.New("failed"))})
+	r.notifyResult("lotus", []reflect.Value{reflect.ValueOf(errors.New("failed"))})
+	assert.Equal(t, []interface{}{1}, got)
+}
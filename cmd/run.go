@@ -31,26 +31,69 @@ func Run(cctx *cli.Context) error {
 	ctx, cancel := context.WithCancel(cctx.Context)
 	defer cancel()
 
-	vAPI, vClose, err := v1.DialFullNodeRPC(ctx, vURL, vToken, nil)
+	vSub := newEthSubHandler()
+	vAPI, vClose, err := v1.DialFullNodeRPC(ctx, vURL, vToken, nil, v1.FullNodeWithRPCOtpions(append(rpcOptions(), ethSubRPCOptions(vSub)...)...))
 	if err != nil {
 		return fmt.Errorf("create venus rpc error: %v", err)
 	}
 	defer vClose()
 
-	lAPI, lClose, err := newLotusFullNodeRPCV1(ctx, lURL, lToken)
+	lSub := newEthSubHandler()
+	lAPI, lClose, err := newLotusFullNodeRPCV1(ctx, lURL, lToken, append(rpcOptions(), ethSubRPCOptions(lSub)...)...)
 	if err != nil {
 		return fmt.Errorf("create lotus rpc error: %v", err)
 	}
 	defer lClose()
 
+	vTrace, vTraceClose, err := newEthTraceRPC(ctx, vURL, vToken, rpcOptions()...)
+	if err != nil {
+		return fmt.Errorf("create venus eth trace rpc error: %v", err)
+	}
+	defer vTraceClose()
+
+	lTrace, lTraceClose, err := newEthTraceRPC(ctx, lURL, lToken, rpcOptions()...)
+	if err != nil {
+		return fmt.Errorf("create lotus eth trace rpc error: %v", err)
+	}
+	defer lTraceClose()
+
 	head, err := vAPI.ChainHead(ctx)
 	if err != nil {
 		return err
 	}
 
+	stateFile := cctx.String("state-file")
+	resume := cctx.Bool("resume")
+	var cp *checkpoint
+	if resume {
+		cp, err = loadCheckpoint(stateFile)
+		if err != nil {
+			return fmt.Errorf("load checkpoint error: %v", err)
+		}
+		if cp == nil {
+			fmt.Println("not found checkpoint in", stateFile)
+			resume = false
+		}
+	}
+
 	var currentTS *types.TipSet
 	var startHeight abi.ChainEpoch
-	if cctx.IsSet("start-height") {
+	if cp != nil {
+		startHeight = cp.Height + 1
+		ts, err := vAPI.ChainGetTipSetByHeight(ctx, cp.Height, head.Key())
+		if err != nil {
+			return fmt.Errorf("get tipset of checkpoint %d error: %v", cp.Height, err)
+		}
+		if !ts.Key().Equals(cp.TipSetKey) {
+			// the tipset of checkpoint was reverted, compare it again
+			fmt.Printf("tipset at height %d changed, %v != %v\n", cp.Height, ts.Key(), cp.TipSetKey)
+			startHeight = cp.Height
+		}
+		if startHeight > head.Height() {
+			startHeight = head.Height()
+		}
+		fmt.Println("resume from height", startHeight)
+	} else if cctx.IsSet("start-height") {
 		startHeight = abi.ChainEpoch(cctx.Int("start-height"))
 		if startHeight > head.Height() {
 			startHeight = head.Height()
@@ -71,20 +114,48 @@ func Run(cctx *cli.Context) error {
 		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
 	}()
 
-	dp, err := newDataProvider(ctx, vAPI)
+	methodTimeouts, err := parseMethodTimeouts(cctx.StringSlice("method-timeout"))
+	if err != nil {
+		return err
+	}
+	timeouts := newCallTimeouts(cctx.Duration("timeout"), methodTimeouts)
+
+	dp, err := newDataProvider(ctx, vAPI, timeouts)
 	if err != nil {
 		return fmt.Errorf("new data provider error: %v", err)
 	}
 
 	r := newRegister()
-	ac := newAPICompare(ctx, vAPI, lAPI, dp, cctx.Int("concurrency"))
+	ac := newAPICompare(ctx, vAPI, lAPI, vTrace, lTrace, dp, cctx.Int("concurrency"), timeouts, &compareConfig{
+		fullMarketDeals:  cctx.Bool("full-market-deals"),
+		gasFeeTolerance:  cctx.Float64("gas-fee-tolerance"),
+		exhaustiveReplay: cctx.Bool("exhaustive-replay"),
+	})
 	if err := r.registerAPICompare(ac); err != nil {
 		return err
 	}
 
-	mgr := newCompareMgr(ctx, vAPI, lAPI, dp, r, currentTS)
+	policy, err := parseCatchUpPolicy(cctx.String("catch-up-policy"))
+	if err != nil {
+		return err
+	}
+
+	mgr := newCompareMgr(ctx, vAPI, lAPI, dp, r, currentTS, &mgrConfig{
+		stateFile: stateFile,
+		resume:    resume,
+		policy:    policy,
+		maxLag:    cctx.Int("max-lag"),
+		timeouts:  timeouts,
+	})
 	go mgr.start()
 
+	if window := cctx.Int("eth-sub-window"); window > 0 {
+		go newEthSubCompare(ctx, vAPI, lAPI, vSub, lSub, window).start()
+	}
+	if window := cctx.Int("chain-notify-window"); window > 0 {
+		go newChainNotifyCompare(ctx, vAPI, lAPI, window).start()
+	}
+
 	<-c
 
 	return nil
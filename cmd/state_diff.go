@@ -0,0 +1,322 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	hamt "github.com/filecoin-project/go-hamt-ipld/v3"
+	actorstypes "github.com/filecoin-project/go-state-types/actors"
+	"github.com/filecoin-project/go-state-types/builtin/v10/account"
+	"github.com/filecoin-project/go-state-types/builtin/v10/cron"
+	"github.com/filecoin-project/go-state-types/builtin/v10/datacap"
+	"github.com/filecoin-project/go-state-types/builtin/v10/evm"
+	init10 "github.com/filecoin-project/go-state-types/builtin/v10/init"
+	"github.com/filecoin-project/go-state-types/builtin/v10/market"
+	"github.com/filecoin-project/go-state-types/builtin/v10/miner"
+	"github.com/filecoin-project/go-state-types/builtin/v10/multisig"
+	"github.com/filecoin-project/go-state-types/builtin/v10/paych"
+	"github.com/filecoin-project/go-state-types/builtin/v10/power"
+	"github.com/filecoin-project/go-state-types/builtin/v10/reward"
+	"github.com/filecoin-project/go-state-types/builtin/v10/system"
+	"github.com/filecoin-project/go-state-types/builtin/v10/verifreg"
+	lapi "github.com/filecoin-project/lotus/api"
+	ltypes "github.com/filecoin-project/lotus/chain/types"
+	v1 "github.com/filecoin-project/venus/venus-shared/api/chain/v1"
+	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+const (
+	// the bit width of the hamt of state tree
+	stateTreeBitWidth = 5
+	// the max time to walk the two state trees, as the differing subtrees may be large
+	stateDiffTimeout = 10 * time.Minute
+)
+
+// actorStateTypes is used to name the fields of actor state, fields of the actors of other versions are named by index.
+var actorStateTypes = map[actorstypes.Version]map[string]reflect.Type{
+	actorstypes.Version10: {
+		"account":          reflect.TypeOf(account.State{}),
+		"cron":             reflect.TypeOf(cron.State{}),
+		"datacap":          reflect.TypeOf(datacap.State{}),
+		"evm":              reflect.TypeOf(evm.State{}),
+		"init":             reflect.TypeOf(init10.State{}),
+		"storagemarket":    reflect.TypeOf(market.State{}),
+		"storageminer":     reflect.TypeOf(miner.State{}),
+		"multisig":         reflect.TypeOf(multisig.State{}),
+		"paymentchannel":   reflect.TypeOf(paych.State{}),
+		"storagepower":     reflect.TypeOf(power.State{}),
+		"reward":           reflect.TypeOf(reward.State{}),
+		"system":           reflect.TypeOf(system.State{}),
+		"verifiedregistry": reflect.TypeOf(verifreg.State{}),
+	},
+}
+
+// readObjStore is a read only ipld store, which loads objects by ChainReadObj of a node.
+type readObjStore struct {
+	read func(context.Context, cid.Cid) ([]byte, error)
+}
+
+var _ cbor.IpldStore = (*readObjStore)(nil)
+
+func (s *readObjStore) Get(ctx context.Context, c cid.Cid, out interface{}) error {
+	data, err := s.read(ctx, c)
+	if err != nil {
+		return fmt.Errorf("read %s failed: %v", c, err)
+	}
+	if um, ok := out.(cbg.CBORUnmarshaler); ok {
+		return um.UnmarshalCBOR(bytes.NewReader(data))
+	}
+	return cbor.DecodeInto(data, out)
+}
+
+func (s *readObjStore) Put(ctx context.Context, v interface{}) (cid.Cid, error) {
+	return cid.Undef, fmt.Errorf("read only store")
+}
+
+// fieldDiff is a top level field of actor state which differs.
+type fieldDiff struct {
+	name string
+	v    string
+	l    string
+}
+
+// actorDiff is an actor which differs in the two state trees, vActor or lActor is nil when the actor is missing.
+type actorDiff struct {
+	addr   address.Address
+	name   string
+	vActor *types.Actor
+	lActor *types.Actor
+	fields []fieldDiff
+}
+
+func (d *actorDiff) String() string {
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "actor %s", d.addr)
+	if d.name != "" {
+		fmt.Fprintf(buf, "(%s)", d.name)
+	}
+	switch {
+	case d.vActor == nil:
+		buf.WriteString(" missing in venus")
+		return buf.String()
+	case d.lActor == nil:
+		buf.WriteString(" missing in lotus")
+		return buf.String()
+	}
+	if d.vActor.Code != d.lActor.Code {
+		fmt.Fprintf(buf, ", code %s != %s", d.vActor.Code, d.lActor.Code)
+	}
+	if d.vActor.Nonce != d.lActor.Nonce {
+		fmt.Fprintf(buf, ", nonce %d != %d", d.vActor.Nonce, d.lActor.Nonce)
+	}
+	if !d.vActor.Balance.Equals(d.lActor.Balance) {
+		fmt.Fprintf(buf, ", balance %s != %s", d.vActor.Balance, d.lActor.Balance)
+	}
+	if d.vActor.Head != d.lActor.Head {
+		fmt.Fprintf(buf, ", head %s != %s", d.vActor.Head, d.lActor.Head)
+	}
+	for _, f := range d.fields {
+		fmt.Fprintf(buf, ", %s %s != %s", f.name, f.v, f.l)
+	}
+
+	return buf.String()
+}
+
+type stateDiffer struct {
+	vStore *readObjStore
+	lStore *readObjStore
+	// actor code to actor name
+	names map[cid.Cid]string
+	// actor code to state type, only the actors of known versions have
+	types map[cid.Cid]reflect.Type
+}
+
+func newStateDiffer(ctx context.Context, vAPI v1.FullNode, lAPI lapi.FullNode, key types.TipSetKey) *stateDiffer {
+	sd := &stateDiffer{
+		vStore: &readObjStore{read: vAPI.ChainReadObj},
+		lStore: &readObjStore{read: lAPI.ChainReadObj},
+		names:  make(map[cid.Cid]string),
+		types:  make(map[cid.Cid]reflect.Type),
+	}
+	// only used to name actors and fields, so ignore the error
+	nv, err := vAPI.StateNetworkVersion(ctx, key)
+	if err != nil {
+		return sd
+	}
+	codes, err := vAPI.StateActorCodeCIDs(ctx, nv)
+	if err != nil {
+		return sd
+	}
+	// the fields are named by index when the actors version is unknown
+	av, _ := actorstypes.VersionForNetwork(nv)
+	for name, c := range codes {
+		sd.names[c] = name
+		if typ, ok := actorStateTypes[av][name]; ok {
+			sd.types[c] = typ
+		}
+	}
+
+	return sd
+}
+
+// diffStateRoots loads state tree of vRoot from venus and lRoot from lotus, only walks the differing subtrees
+// of the actors hamt, and returns the actors which differ.
+func (sd *stateDiffer) diffStateRoots(ctx context.Context, vRoot, lRoot cid.Cid) ([]*actorDiff, error) {
+	if vRoot == lRoot {
+		return nil, nil
+	}
+
+	var vsr, lsr ltypes.StateRoot
+	if err := sd.vStore.Get(ctx, vRoot, &vsr); err != nil {
+		return nil, fmt.Errorf("venus state root: %v", err)
+	}
+	if err := sd.lStore.Get(ctx, lRoot, &lsr); err != nil {
+		return nil, fmt.Errorf("lotus state root: %v", err)
+	}
+	if vsr.Version != lsr.Version {
+		return nil, fmt.Errorf("state tree version %d != %d", vsr.Version, lsr.Version)
+	}
+
+	changes, err := hamt.Diff(ctx, sd.vStore, sd.lStore, vsr.Actors, lsr.Actors, hamt.UseTreeBitWidth(stateTreeBitWidth))
+	if err != nil {
+		return nil, fmt.Errorf("diff actors failed: %v", err)
+	}
+
+	out := make([]*actorDiff, 0, len(changes))
+	for _, change := range changes {
+		d, err := sd.diffActor(ctx, change)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+
+	return out, nil
+}
+
+func (sd *stateDiffer) diffActor(ctx context.Context, change *hamt.Change) (*actorDiff, error) {
+	addr, err := address.NewFromBytes([]byte(change.Key))
+	if err != nil {
+		return nil, fmt.Errorf("decode address %x failed: %v", change.Key, err)
+	}
+	d := &actorDiff{addr: addr}
+
+	decode := func(raw *cbg.Deferred) (*types.Actor, error) {
+		if raw == nil {
+			return nil, nil
+		}
+		var act types.Actor
+		if err := act.UnmarshalCBOR(bytes.NewReader(raw.Raw)); err != nil {
+			return nil, fmt.Errorf("decode actor %s failed: %v", addr, err)
+		}
+		return &act, nil
+	}
+	// Before is from the state tree of venus, After is from lotus
+	if d.vActor, err = decode(change.Before); err != nil {
+		return nil, err
+	}
+	if d.lActor, err = decode(change.After); err != nil {
+		return nil, err
+	}
+	if d.vActor != nil {
+		d.name = sd.names[d.vActor.Code]
+	} else {
+		d.name = sd.names[d.lActor.Code]
+	}
+
+	if d.vActor == nil || d.lActor == nil || d.vActor.Head == d.lActor.Head || d.vActor.Code != d.lActor.Code {
+		return d, nil
+	}
+
+	vData, err := sd.vStore.read(ctx, d.vActor.Head)
+	if err != nil {
+		return nil, fmt.Errorf("venus read head of %s failed: %v", addr, err)
+	}
+	lData, err := sd.lStore.read(ctx, d.lActor.Head)
+	if err != nil {
+		return nil, fmt.Errorf("lotus read head of %s failed: %v", addr, err)
+	}
+	d.fields, err = diffStateFields(sd.types[d.vActor.Code], vData, lData)
+	if err != nil {
+		return nil, fmt.Errorf("diff state of %s failed: %v", addr, err)
+	}
+
+	return d, nil
+}
+
+// diffStateFields splits the two cbor tuples into fields, and returns the fields which differ,
+// fields are named by typ if not nil.
+func diffStateFields(typ reflect.Type, vData, lData []byte) ([]fieldDiff, error) {
+	vFields, err := splitTuple(vData)
+	if err != nil {
+		return nil, err
+	}
+	lFields, err := splitTuple(lData)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []fieldDiff
+	for i := 0; i < len(vFields) || i < len(lFields); i++ {
+		var v, l []byte
+		if i < len(vFields) {
+			v = vFields[i]
+		}
+		if i < len(lFields) {
+			l = lFields[i]
+		}
+		if bytes.Equal(v, l) {
+			continue
+		}
+		name := fmt.Sprintf("field %d", i)
+		if typ != nil && i < typ.NumField() && len(vFields) == typ.NumField() {
+			name = typ.Field(i).Name
+		}
+		out = append(out, fieldDiff{name: name, v: formatField(v), l: formatField(l)})
+	}
+
+	return out, nil
+}
+
+// splitTuple returns the raw bytes of every element of a cbor array.
+func splitTuple(data []byte) ([][]byte, error) {
+	r := bytes.NewReader(data)
+	maj, n, err := cbg.CborReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if maj != cbg.MajArray {
+		return nil, fmt.Errorf("expect cbor array, got major type %d", maj)
+	}
+
+	out := make([][]byte, 0, n)
+	for i := uint64(0); i < n; i++ {
+		var d cbg.Deferred
+		if err := d.UnmarshalCBOR(r); err != nil {
+			return nil, fmt.Errorf("field %d: %v", i, err)
+		}
+		out = append(out, d.Raw)
+	}
+
+	return out, nil
+}
+
+// formatField shows cid field as cid, others as hex.
+func formatField(raw []byte) string {
+	if raw == nil {
+		return "<nil>"
+	}
+	if c, err := cbg.ReadCid(bytes.NewReader(raw)); err == nil {
+		return c.String()
+	}
+	return hex.EncodeToString(raw)
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	hamt "github.com/filecoin-project/go-hamt-ipld/v3"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/builtin/v10/account"
+	ltypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/stretchr/testify/assert"
+)
+
+func memReadObjStore(store cbor.IpldStore) *readObjStore {
+	return &readObjStore{read: func(ctx context.Context, c cid.Cid) ([]byte, error) {
+		blk, err := store.(*cbor.BasicIpldStore).Blocks.Get(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		return blk.RawData(), nil
+	}}
+}
+
+func TestDiffStateRoots(t *testing.T) {
+	ctx := context.Background()
+	vStore := cbor.NewMemCborStore()
+	lStore := cbor.NewMemCborStore()
+
+	putState := func(store cbor.IpldStore, addr address.Address) cid.Cid {
+		c, err := store.Put(ctx, &account.State{Address: addr})
+		assert.NoError(t, err)
+		return c
+	}
+	code, err := abi.CidBuilder.Sum([]byte("account"))
+	assert.NoError(t, err)
+	a1, _ := address.NewIDAddress(1000)
+	a2, _ := address.NewIDAddress(1001)
+	a3, _ := address.NewIDAddress(1002)
+	k1, _ := address.NewIDAddress(1)
+	k2, _ := address.NewIDAddress(2)
+
+	buildTree := func(store cbor.IpldStore, actors map[address.Address]*types.Actor) cid.Cid {
+		node, err := hamt.NewNode(store, hamt.UseTreeBitWidth(stateTreeBitWidth))
+		assert.NoError(t, err)
+		for addr, act := range actors {
+			assert.NoError(t, node.Set(ctx, string(addr.Bytes()), act))
+		}
+		assert.NoError(t, node.Flush(ctx))
+		actorsRoot, err := store.Put(ctx, node)
+		assert.NoError(t, err)
+		info, err := store.Put(ctx, &ltypes.StateInfo0{})
+		assert.NoError(t, err)
+		root, err := store.Put(ctx, &ltypes.StateRoot{Version: ltypes.StateTreeVersion4, Actors: actorsRoot, Info: info})
+		assert.NoError(t, err)
+		return root
+	}
+
+	same := &types.Actor{Code: code, Head: putState(vStore, k1), Balance: abi.NewTokenAmount(1)}
+	putState(lStore, k1)
+	vRoot := buildTree(vStore, map[address.Address]*types.Actor{
+		a1: same,
+		a2: {Code: code, Head: putState(vStore, k1), Nonce: 1, Balance: abi.NewTokenAmount(1)},
+	})
+	lRoot := buildTree(lStore, map[address.Address]*types.Actor{
+		a1: same,
+		a2: {Code: code, Head: putState(lStore, k2), Nonce: 2, Balance: abi.NewTokenAmount(1)},
+		a3: {Code: code, Head: same.Head, Balance: abi.NewTokenAmount(1)},
+	})
+
+	sd := &stateDiffer{
+		vStore: memReadObjStore(vStore),
+		lStore: memReadObjStore(lStore),
+		names:  map[cid.Cid]string{code: "account"},
+		types:  map[cid.Cid]reflect.Type{code: reflect.TypeOf(account.State{})},
+	}
+	diffs, err := sd.diffStateRoots(ctx, vRoot, lRoot)
+	assert.NoError(t, err)
+	assert.Len(t, diffs, 2)
+	for _, d := range diffs {
+		switch d.addr {
+		case a2:
+			assert.Equal(t, "account", d.name)
+			assert.Equal(t, uint64(1), d.vActor.Nonce)
+			assert.Equal(t, uint64(2), d.lActor.Nonce)
+			assert.Len(t, d.fields, 1)
+			assert.Equal(t, "Address", d.fields[0].name)
+		case a3:
+			assert.Nil(t, d.vActor)
+			assert.NotNil(t, d.lActor)
+			assert.Contains(t, d.String(), "missing in venus")
+		default:
+			t.Errorf("unexpected actor %s", d.addr)
+		}
+	}
+
+	diffs, err = sd.diffStateRoots(ctx, vRoot, vRoot)
+	assert.NoError(t, err)
+	assert.Empty(t, diffs)
+}
+
+func TestDiffStateFields(t *testing.T) {
+	// [1, h'01']
+	v := []byte{0x82, 0x01, 0x41, 0x01}
+	// [1, h'02']
+	l := []byte{0x82, 0x01, 0x41, 0x02}
+
+	fields, err := diffStateFields(nil, v, v)
+	assert.NoError(t, err)
+	assert.Empty(t, fields)
+
+	fields, err = diffStateFields(nil, v, l)
+	assert.NoError(t, err)
+	assert.Equal(t, []fieldDiff{{name: "field 1", v: "4101", l: "4102"}}, fields)
+
+	type state struct {
+		A int
+		B []byte
+	}
+	fields, err = diffStateFields(reflect.TypeOf(state{}), v, l)
+	assert.NoError(t, err)
+	assert.Equal(t, "B", fields[0].name)
+
+	// [1]
+	fields, err = diffStateFields(reflect.TypeOf(state{}), v, []byte{0x81, 0x01})
+	assert.NoError(t, err)
+	assert.Equal(t, []fieldDiff{{name: "B", v: "4101", l: "<nil>"}}, fields)
+
+	_, err = diffStateFields(nil, []byte{0x01}, l)
+	assert.Error(t, err)
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const defaultCallTimeout = 3 * time.Minute
+
+func newCallTimeouts(defaultTimeout time.Duration, methods map[string]time.Duration) *callTimeouts {
+	if defaultTimeout <= 0 {
+		defaultTimeout = defaultCallTimeout
+	}
+	if methods == nil {
+		methods = map[string]time.Duration{}
+	}
+
+	return &callTimeouts{
+		defaultTimeout: defaultTimeout,
+		methods:        methods,
+	}
+}
+
+// callTimeouts holds the maximum time a compared api call may take, per method.
+type callTimeouts struct {
+	defaultTimeout time.Duration
+	methods        map[string]time.Duration
+}
+
+func (ct *callTimeouts) get(method string) time.Duration {
+	if ct == nil {
+		return defaultCallTimeout
+	}
+	if t, ok := ct.methods[method]; ok {
+		return t
+	}
+
+	return ct.defaultTimeout
+}
+
+// parseMethodTimeouts parses values like `StateWaitMsg=10m`.
+func parseMethodTimeouts(list []string) (map[string]time.Duration, error) {
+	methods := make(map[string]time.Duration, len(list))
+	for _, item := range list {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || len(kv[0]) == 0 {
+			return nil, fmt.Errorf("invalid method timeout %s, expect <method>=<duration>", item)
+		}
+		t, err := time.ParseDuration(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid method timeout %s: %v", item, err)
+		}
+		if t <= 0 {
+			return nil, fmt.Errorf("invalid method timeout %s: must be positive", item)
+		}
+		methods[kv[0]] = t
+	}
+
+	return methods, nil
+}
+
+// timeoutError means at least one node did not answer a call in time.
+type timeoutError struct {
+	method  string
+	timeout time.Duration
+	nodes   []string
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("call %s timeout after %v, %s not answered", e.method, e.timeout, strings.Join(e.nodes, " and "))
+}
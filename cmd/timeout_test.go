@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMethodTimeouts(t *testing.T) {
+	methods, err := parseMethodTimeouts([]string{"StateWaitMsg=10m", "StateReplay=30s"})
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, methods["StateWaitMsg"])
+	assert.Equal(t, 30*time.Second, methods["StateReplay"])
+
+	ct := newCallTimeouts(0, methods)
+	assert.Equal(t, 10*time.Minute, ct.get("StateWaitMsg"))
+	assert.Equal(t, defaultCallTimeout, ct.get("ChainHead"))
+
+	for _, item := range []string{"StateWaitMsg", "=10m", "StateWaitMsg=abc", "StateWaitMsg=-1s"} {
+		_, err := parseMethodTimeouts([]string{item})
+		assert.Error(t, err, item)
+	}
+}
@@ -1,11 +1,13 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"reflect"
 
+	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-jsonrpc"
 	"github.com/filecoin-project/go-state-types/big"
 	lapi "github.com/filecoin-project/lotus/api"
@@ -17,7 +19,7 @@ import (
 	"github.com/ipfs/go-cid"
 )
 
-func newLotusFullNodeRPCV1(ctx context.Context, url, token string) (lapi.FullNode, jsonrpc.ClientCloser, error) {
+func newLotusFullNodeRPCV1(ctx context.Context, url, token string, opts ...jsonrpc.Option) (lapi.FullNode, jsonrpc.ClientCloser, error) {
 	apiInfo := api.NewAPIInfo(url, token)
 	endpoint, err := apiInfo.DialArgs("v1")
 	if err != nil {
@@ -26,7 +28,7 @@ func newLotusFullNodeRPCV1(ctx context.Context, url, token string) (lapi.FullNod
 
 	var res v1api.FullNodeStruct
 	closer, err := jsonrpc.NewMergeClient(ctx, endpoint, "Filecoin",
-		api.GetInternalStructs(&res), apiInfo.AuthHeader())
+		api.GetInternalStructs(&res), apiInfo.AuthHeader(), opts...)
 
 	return &res, closer, err
 }
@@ -60,6 +62,28 @@ func toLotusEthMessageMatch(src *types.MessageMatch) lapi.MessageMatch {
 	}
 }
 
+func toLotusMessageSendSpec(src *types.MessageSendSpec) *lapi.MessageSendSpec {
+	if src == nil {
+		return nil
+	}
+	return &lapi.MessageSendSpec{
+		MaxFee:            src.MaxFee,
+		GasOverEstimation: src.GasOverEstimation,
+		GasOverPremium:    src.GasOverPremium,
+	}
+}
+
+func toLotusMessagePrototypes(src []*types.MessagePrototype) []*lapi.MessagePrototype {
+	out := make([]*lapi.MessagePrototype, 0, len(src))
+	for _, p := range src {
+		out = append(out, &lapi.MessagePrototype{
+			Message:    *toLotusMsg(&p.Message),
+			ValidNonce: p.ValidNonce,
+		})
+	}
+	return out
+}
+
 func toLotusEthCall(src types.EthCall) ethtypes.EthCall {
 	return ethtypes.EthCall{
 		From:     (*ethtypes.EthAddress)(src.From),
@@ -71,6 +95,29 @@ func toLotusEthCall(src types.EthCall) ethtypes.EthCall {
 	}
 }
 
+func toLotusEthFilterSpec(src *types.EthFilterSpec) *ethtypes.EthFilterSpec {
+	if src == nil {
+		return nil
+	}
+	out := &ethtypes.EthFilterSpec{
+		FromBlock: src.FromBlock,
+		ToBlock:   src.ToBlock,
+		BlockHash: (*ethtypes.EthHash)(src.BlockHash),
+	}
+	for _, addr := range src.Address {
+		out.Address = append(out.Address, ethtypes.EthAddress(addr))
+	}
+	for _, hashes := range src.Topics {
+		var list ethtypes.EthHashList
+		for _, h := range hashes {
+			list = append(list, ethtypes.EthHash(h))
+		}
+		out.Topics = append(out.Topics, list)
+	}
+
+	return out
+}
+
 func checkByJSON(a, b interface{}) error {
 	d, d2, err := toJSON(a, b)
 	if err != nil {
@@ -97,7 +144,6 @@ func toJSON(a, b interface{}) ([]byte, []byte, error) {
 	return d, d2, nil
 }
 
-// nolint
 func unmarshalAny[T any](a interface{}) (T, error) {
 	var t T
 
@@ -110,6 +156,12 @@ func unmarshalAny[T any](a interface{}) (T, error) {
 }
 
 func checkInvocResult(vRes *types.InvocResult, lRes *lapi.InvocResult) error {
+	if vRes == nil || lRes == nil {
+		if vRes == nil && lRes == nil {
+			return nil
+		}
+		return fmt.Errorf("one is nil %v %v", vRes == nil, lRes == nil)
+	}
 	if vRes.MsgCid != lRes.MsgCid {
 		return fmt.Errorf("msg cid not match %v != %v", vRes.MsgCid, lRes.MsgCid)
 	}
@@ -125,6 +177,9 @@ func checkInvocResult(vRes *types.InvocResult, lRes *lapi.InvocResult) error {
 	if err := checkByJSON(vRes.GasCost, lRes.GasCost); err != nil {
 		return fmt.Errorf("gas cost: %+v != %+v", vRes.GasCost, lRes.GasCost)
 	}
+	if vRes.Error != lRes.Error {
+		return fmt.Errorf("error not match %s != %s", vRes.Error, lRes.Error)
+	}
 
 	return check(vRes.ExecutionTrace, lRes.ExecutionTrace)
 }
@@ -155,6 +210,55 @@ func check(vTrace types.ExecutionTrace, lTrace ltypes.ExecutionTrace) error {
 	return nil
 }
 
+// checkBlockMessages checks the count, order and content of all messages, including signatures of secp messages.
+func checkBlockMessages(vMsgs *types.BlockMessages, lMsgs *lapi.BlockMessages) error {
+	if vMsgs == nil || lMsgs == nil {
+		if vMsgs == nil && lMsgs == nil {
+			return nil
+		}
+		return fmt.Errorf("one is nil %v %v", vMsgs == nil, lMsgs == nil)
+	}
+
+	if len(vMsgs.BlsMessages) != len(lMsgs.BlsMessages) {
+		return fmt.Errorf("bls messages length %d != %d", len(vMsgs.BlsMessages), len(lMsgs.BlsMessages))
+	}
+	if len(vMsgs.SecpkMessages) != len(lMsgs.SecpkMessages) {
+		return fmt.Errorf("secp messages length %d != %d", len(vMsgs.SecpkMessages), len(lMsgs.SecpkMessages))
+	}
+	if len(vMsgs.Cids) != len(lMsgs.Cids) {
+		return fmt.Errorf("cids length %d != %d", len(vMsgs.Cids), len(lMsgs.Cids))
+	}
+
+	for i, msg := range vMsgs.BlsMessages {
+		lMsg := lMsgs.BlsMessages[i]
+		if msg.Cid() != lMsg.Cid() {
+			return fmt.Errorf("bls message %d cid %s != %s", i, msg.Cid(), lMsg.Cid())
+		}
+		if !equal(msg, lMsg) {
+			return fmt.Errorf("bls message %d not match %+v != %+v", i, msg, lMsg)
+		}
+	}
+	for i, msg := range vMsgs.SecpkMessages {
+		lMsg := lMsgs.SecpkMessages[i]
+		if msg.Cid() != lMsg.Cid() {
+			return fmt.Errorf("secp message %d cid %s != %s", i, msg.Cid(), lMsg.Cid())
+		}
+		if !equal(msg.Message, lMsg.Message) {
+			return fmt.Errorf("secp message %d not match %+v != %+v", i, msg.Message, lMsg.Message)
+		}
+		if msg.Signature.Type != lMsg.Signature.Type || !bytes.Equal(msg.Signature.Data, lMsg.Signature.Data) {
+			return fmt.Errorf("secp message %d signature %v != %v", i, msg.Signature, lMsg.Signature)
+		}
+	}
+	for i, c := range vMsgs.Cids {
+		if c != lMsgs.Cids[i] {
+			return fmt.Errorf("cid %d %s != %s", i, c, lMsgs.Cids[i])
+		}
+	}
+
+	return nil
+}
+
 func tsEquals(ts *types.TipSet, ots *ltypes.TipSet) error {
 	if ts == nil && ots == nil {
 		return nil
@@ -198,6 +302,28 @@ func bigIntEqual(a, b *big.Int) error {
 	return nil
 }
 
+// bigIntWithinTolerance checks |a-b| <= tolerance * max(|a|, |b|).
+func bigIntWithinTolerance(a, b big.Int, tolerance float64) error {
+	if a.Int == nil || b.Int == nil {
+		return bigIntEqual(&a, &b)
+	}
+	abs := func(i big.Int) big.Int {
+		if i.LessThan(big.Zero()) {
+			return big.Sub(big.Zero(), i)
+		}
+		return i
+	}
+	diff := abs(big.Sub(a, b))
+	max := big.Max(abs(a), abs(b))
+	// use parts per million to avoid float
+	allowed := big.Div(big.Mul(max, big.NewInt(int64(tolerance*1e6))), big.NewInt(1e6))
+	if diff.GreaterThan(allowed) {
+		return fmt.Errorf("not match %v != %v, out of tolerance %v", a, b, tolerance)
+	}
+
+	return nil
+}
+
 func equal(a, b interface{}) bool {
 	av := reflect.ValueOf(a)
 	bv := reflect.ValueOf(b)
@@ -310,6 +436,29 @@ func resultCheckWithInvocResult(msg cid.Cid, o1, o2 interface{}) error {
 	return nil
 }
 
+func containAddress(list []address.Address, addr address.Address) bool {
+	for _, a := range list {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// appendRecent appends v if not exist, and only keeps the last limit ones.
+func appendRecent[T comparable](list []T, limit int, v T) []T {
+	for _, item := range list {
+		if item == v {
+			return list
+		}
+	}
+	list = append(list, v)
+	if len(list) > limit {
+		list = list[len(list)-limit:]
+	}
+	return list
+}
+
 func toInterface(list ...interface{}) []interface{} {
 	i := make([]interface{}, 0, len(list))
 	i = append(i, list...)
@@ -6,11 +6,14 @@ import (
 	"testing"
 
 	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/crypto"
 	"github.com/filecoin-project/lotus/api"
 	ltypes "github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/lotus/chain/types/ethtypes"
 	"github.com/filecoin-project/venus/venus-shared/testutil"
 	"github.com/filecoin-project/venus/venus-shared/types"
+	"github.com/ipfs/go-cid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -60,3 +63,75 @@ func TestLogsBloomMarshal(t *testing.T) {
 	fmt.Printf("%s \n %s\n", d, d2)
 	assert.Equal(t, d, d2)
 }
+
+func TestBigIntWithinTolerance(t *testing.T) {
+	assert.NoError(t, bigIntWithinTolerance(big.NewInt(100), big.NewInt(104), 0.05))
+	assert.NoError(t, bigIntWithinTolerance(big.NewInt(-100), big.NewInt(-104), 0.05))
+	assert.Error(t, bigIntWithinTolerance(big.NewInt(100), big.NewInt(110), 0.05))
+	assert.Error(t, bigIntWithinTolerance(big.NewInt(100), big.NewInt(101), 0))
+}
+
+func TestCheckBlockMessages(t *testing.T) {
+	toLotus := func(msg *types.Message) *ltypes.Message {
+		return &ltypes.Message{
+			Version:    msg.Version,
+			To:         msg.To,
+			From:       msg.From,
+			Nonce:      msg.Nonce,
+			Value:      msg.Value,
+			GasLimit:   msg.GasLimit,
+			GasFeeCap:  msg.GasFeeCap,
+			GasPremium: msg.GasPremium,
+			Method:     msg.Method,
+			Params:     msg.Params,
+		}
+	}
+
+	var blsMsg, secpMsg types.Message
+	testutil.Provide(t, &blsMsg)
+	testutil.Provide(t, &secpMsg)
+	signed := &types.SignedMessage{
+		Message:   secpMsg,
+		Signature: crypto.Signature{Type: crypto.SigTypeSecp256k1, Data: []byte{1, 2, 3}},
+	}
+
+	build := func() (*types.BlockMessages, *api.BlockMessages) {
+		vMsgs := &types.BlockMessages{
+			BlsMessages:   []*types.Message{&blsMsg},
+			SecpkMessages: []*types.SignedMessage{signed},
+			Cids:          []cid.Cid{blsMsg.Cid(), signed.Cid()},
+		}
+		lMsgs := &api.BlockMessages{
+			BlsMessages: []*ltypes.Message{toLotus(&blsMsg)},
+			SecpkMessages: []*ltypes.SignedMessage{{
+				Message:   *toLotus(&secpMsg),
+				Signature: crypto.Signature{Type: crypto.SigTypeSecp256k1, Data: []byte{1, 2, 3}},
+			}},
+			Cids: []cid.Cid{blsMsg.Cid(), signed.Cid()},
+		}
+		return vMsgs, lMsgs
+	}
+
+	vMsgs, lMsgs := build()
+	assert.NoError(t, checkBlockMessages(vMsgs, lMsgs))
+
+	// different signature
+	vMsgs, lMsgs = build()
+	lMsgs.SecpkMessages[0].Signature.Data = []byte{3, 2, 1}
+	assert.Error(t, checkBlockMessages(vMsgs, lMsgs))
+
+	// different order
+	vMsgs, lMsgs = build()
+	lMsgs.Cids[0], lMsgs.Cids[1] = lMsgs.Cids[1], lMsgs.Cids[0]
+	assert.Error(t, checkBlockMessages(vMsgs, lMsgs))
+
+	// extra message in venus
+	vMsgs, lMsgs = build()
+	vMsgs.BlsMessages = append(vMsgs.BlsMessages, &secpMsg)
+	assert.Error(t, checkBlockMessages(vMsgs, lMsgs))
+
+	// extra message in lotus
+	vMsgs, lMsgs = build()
+	lMsgs.Cids = append(lMsgs.Cids, secpMsg.Cid())
+	assert.Error(t, checkBlockMessages(vMsgs, lMsgs))
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/simlecode/api-compare/cmd"
 	"github.com/urfave/cli/v2"
@@ -37,10 +38,60 @@ func main() {
 				Name:  "start-height",
 				Usage: "Start comparing the height of the API",
 			},
+			&cli.BoolFlag{
+				Name:  "resume",
+				Usage: "Resume comparing from the height recorded in the state file",
+			},
+			&cli.StringFlag{
+				Name:  "state-file",
+				Value: "apicompare-state.json",
+				Usage: "File to persist the last compared height",
+			},
+			&cli.StringFlag{
+				Name:  "catch-up-policy",
+				Value: "sequential",
+				Usage: "How to catch up when lagging behind the head, sequential: compare every height, " +
+					"sample: compare part of the heights, skip: jump to the latest height",
+			},
+			&cli.IntFlag{
+				Name:  "max-lag",
+				Value: 20,
+				Usage: "The lag allowed before sample or skip heights",
+			},
+			&cli.BoolFlag{
+				Name:  "full-market-deals",
+				Usage: "Compare all deals returned by StateMarketDeals, otherwise only compare sampled deals by StateMarketStorageDeal",
+			},
+			&cli.BoolFlag{
+				Name:  "exhaustive-replay",
+				Usage: "Replay and call every message of the compared tipset, and compare the state root computed by StateCompute",
+			},
+			&cli.Float64Flag{
+				Name:  "gas-fee-tolerance",
+				Value: 0.05,
+				Usage: "Relative tolerance of estimated fee cap and gas premium, eg: 0.05 means 5%",
+			},
 			&cli.IntFlag{
 				Name:  "concurrency",
 				Value: 2,
 			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Value: 3 * time.Minute,
+				Usage: "Default timeout of each compared api call",
+			},
+			&cli.StringSliceFlag{
+				Name:  "method-timeout",
+				Usage: "Timeout of the specified method, eg: StateWaitMsg=10m",
+			},
+			&cli.IntFlag{
+				Name:  "eth-sub-window",
+				Usage: "Compare eth_subscribe notifications of newHeads and logs every window of epochs, requires websocket urls, 0 means disabled",
+			},
+			&cli.IntFlag{
+				Name:  "chain-notify-window",
+				Usage: "Compare ChainNotify head changes of both nodes every window of epochs, 0 means disabled",
+			},
 		},
 		Action: cmd.Run,
 	}